@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"alfredo-go/internal/service"
+	"alfredo-go/pkg/alfred"
+
+	"github.com/spf13/cobra"
+)
+
+var bulkmenuCmd = &cobra.Command{
+	Use:   "bulkmenu [input]",
+	Short: "Show bulk actions for the current query",
+	Long: `Show actions that apply to every task matching the current query.
+Reads the query mode and search from the myMode and myArg environment variables.`,
+	Args:               cobra.RangeArgs(0, 1),
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		actionInput := ""
+		if len(args) > 0 {
+			actionInput = args[0]
+		}
+
+		output, err := taskService.BulkMenu(bulkMode(), os.Getenv("myArg"), actionInput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building bulk menu: %v\n", err)
+			output = &alfred.Output{Items: []alfred.OutputItem{errorItem(err, actionInput)}}
+		}
+
+		jsonOutput, err := output.Marshal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(jsonOutput))
+	},
+}
+
+var bulkCmd = &cobra.Command{
+	Use:   "bulk [action]",
+	Short: "Apply an action to every task matching the current query",
+	Long: `Apply an action (complete, reschedule:<date>, move:#project, label:<name>, priority:<1-4>)
+to every task matching the query in the myMode and myArg environment variables.`,
+	Args:               cobra.ExactArgs(1),
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		summary, err := taskService.BulkApply(bulkMode(), os.Getenv("myArg"), args[0])
+		if errors.Is(err, service.ErrQueued) {
+			fmt.Println("📥 offline: bulk changes queued\nwill sync when Todoist is reachable")
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying bulk action: %v\n", err)
+			fmt.Println(errorMessage(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("🎯 %s!\nBulk action done.\n", summary)
+	},
+}
+
+func bulkMode() string {
+	mode := os.Getenv("myMode")
+	if mode == "" {
+		mode = "all"
+	}
+	return mode
+}
+
+func init() {
+	rootCmd.AddCommand(bulkmenuCmd)
+	rootCmd.AddCommand(bulkCmd)
+}
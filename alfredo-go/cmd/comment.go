@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"alfredo-go/internal/service"
+	"alfredo-go/pkg/alfred"
+
+	"github.com/spf13/cobra"
+)
+
+var commentsCmd = &cobra.Command{
+	Use:   "comments [input]",
+	Short: "List a task's comments",
+	Long: `List the comments on the task in the myTaskID environment variable, filtered by input.
+Non-empty input is offered as a new comment.`,
+	Args:               cobra.RangeArgs(0, 1),
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		input := ""
+		if len(args) > 0 {
+			input = args[0]
+		}
+
+		output, err := taskService.Comments(os.Getenv("myTaskID"), input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing comments: %v\n", err)
+			output = &alfred.Output{Items: []alfred.OutputItem{errorItem(err, input)}}
+		}
+
+		jsonOutput, err := output.Marshal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(jsonOutput))
+	},
+}
+
+var commentCmd = &cobra.Command{
+	Use:                "comment [text]",
+	Short:              "Add a comment to a task",
+	Long:               `Add a comment to the task in the myTaskID environment variable.`,
+	Args:               cobra.ExactArgs(1),
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		taskID := os.Getenv("myTaskID")
+		if taskID == "" {
+			fmt.Fprintln(os.Stderr, "Error: myTaskID not set")
+			fmt.Println("❌ missing task ID\ncheck debugger")
+			os.Exit(1)
+		}
+		// Selecting an existing comment passes no text; nothing to do
+		if strings.TrimSpace(args[0]) == "" {
+			return
+		}
+
+		err := taskService.AddComment(taskID, args[0])
+		if errors.Is(err, service.ErrQueued) {
+			fmt.Println("📥 offline: comment queued\nwill sync when Todoist is reachable")
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding comment: %v\n", err)
+			fmt.Println(errorMessage(err))
+			os.Exit(1)
+		}
+
+		fmt.Println("💬 comment added!\nThanks for the update.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(commentsCmd)
+	rootCmd.AddCommand(commentCmd)
+}
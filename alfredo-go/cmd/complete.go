@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"alfredo-go/internal/service"
+
 	"github.com/spf13/cobra"
 )
 
@@ -20,13 +23,25 @@ var completeCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		err := taskService.CompleteTask(taskID)
+		next, err := taskService.CompleteTask(taskID)
+		if errors.Is(err, service.ErrQueued) {
+			fmt.Println("📥 offline: completion queued\nwill sync when Todoist is reachable")
+			return
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error completing task: %v\n", err)
-			fmt.Println("❌ server error\ncheck debugger")
+			fmt.Println(errorMessage(err))
 			os.Exit(1)
 		}
 
+		if next != nil {
+			when := next.Due.Date
+			if next.Due.String != "" {
+				when += " (" + next.Due.String + ")"
+			}
+			fmt.Printf("🔁 task completed!\nnext: %s\n", when)
+			return
+		}
 		fmt.Println("🎯 task completed!\nWell done 💪")
 	},
 }
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 
+	"alfredo-go/internal/service"
+
 	"github.com/spf13/cobra"
 )
 
@@ -19,11 +22,13 @@ var createCmd = &cobra.Command{
 		taskLabels := os.Getenv("myTagString")
 		taskProjectID := os.Getenv("myProjectID")
 		taskSectionID := os.Getenv("mySectionID")
+		taskParentID := os.Getenv("myParentID")
 		myDueDate := os.Getenv("myDueDate")
 		myDueString := os.Getenv("myDueString")
 		myDueLang := os.Getenv("myDueLang")
 		myDeadline := os.Getenv("myDeadline")
 		myPriorityStr := os.Getenv("myPriority")
+		myDescription := os.Getenv("myDescription")
 
 		priority := 1
 		if myPriorityStr != "" {
@@ -50,14 +55,28 @@ var createCmd = &cobra.Command{
 			}
 		}
 
-		err := taskService.CreateTask(taskText, taskLabels, taskProjectID, taskSectionID, myDueDate, myDueString, myDueLang, priority, myDeadline, deadlineLang)
+		task, err := taskService.CreateTask(taskText, taskLabels, taskProjectID, taskSectionID, taskParentID, myDueDate, myDueString, myDueLang, priority, myDeadline, deadlineLang, myDescription)
+		if errors.Is(err, service.ErrQueued) {
+			fmt.Println("📥 offline: task queued\nwill sync when Todoist is reachable")
+			return
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating task: %v\n", err)
-			fmt.Println("❌ server error\ncheck debugger")
+			fmt.Println(errorMessage(err))
 			os.Exit(1)
 		}
+		if task == nil {
+			fmt.Println("🎯 task created!\nWell done.")
+			return
+		}
 
-		fmt.Println("🎯 task created!\nWell done.")
+		// Tell Alfred what Todoist made of the task so it can be checked and opened
+		data, err := taskService.CreatedOutput(task).Marshal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
 	},
 }
 
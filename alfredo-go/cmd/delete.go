@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"alfredo-go/internal/service"
+
 	"github.com/spf13/cobra"
 )
 
@@ -21,9 +24,13 @@ var deleteCmd = &cobra.Command{
 		}
 
 		err := taskService.DeleteTask(taskID)
+		if errors.Is(err, service.ErrQueued) {
+			fmt.Println("📥 offline: deletion queued\nwill sync when Todoist is reachable")
+			return
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error deleting task: %v\n", err)
-			fmt.Println("❌ server error\ncheck debugger")
+			fmt.Println(errorMessage(err))
 			os.Exit(1)
 		}
 
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 
+	"alfredo-go/internal/service"
+
 	"github.com/spf13/cobra"
 )
 
@@ -20,11 +23,13 @@ var editCmd = &cobra.Command{
 		taskLabels := os.Getenv("myTagString")
 		taskProjectID := os.Getenv("myProjectID")
 		taskSectionID := os.Getenv("mySectionID")
+		taskParentID := os.Getenv("myParentID")
 		myDueDate := os.Getenv("myDueDate")
 		myDueString := os.Getenv("myDueString")
 		myDueLang := os.Getenv("myDueLang")
 		myDeadline := os.Getenv("myDeadline")
 		myPriorityStr := os.Getenv("myPriority")
+		myDescription := os.Getenv("myDescription")
 
 		priority := 1
 		if myPriorityStr != "" {
@@ -57,10 +62,20 @@ var editCmd = &cobra.Command{
 			}
 		}
 
-		err := taskService.EditTask(taskID, taskText, taskLabels, taskProjectID, taskSectionID, myDueDate, myDueString, myDueLang, priority, myDeadline, deadlineLang)
+		// Without a // the description is left alone (see EditTask)
+		var description *string
+		if os.Getenv("myDescGiven") != "" {
+			description = &myDescription
+		}
+
+		err := taskService.EditTask(taskID, taskText, taskLabels, taskProjectID, taskSectionID, taskParentID, myDueDate, myDueString, myDueLang, priority, myDeadline, deadlineLang, description)
+		if errors.Is(err, service.ErrQueued) {
+			fmt.Println("📥 offline: edit queued\nwill sync when Todoist is reachable")
+			return
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error editing task: %v\n", err)
-			fmt.Println("❌ server error\ncheck debugger")
+			fmt.Println(errorMessage(err))
 			os.Exit(1)
 		}
 
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+
+	"alfredo-go/internal/service"
+	"alfredo-go/pkg/alfred"
+	"alfredo-go/pkg/todoist"
+)
+
+// errorItem returns the Alfred item shown when a script filter can't load
+// data; arg is passed through so that pressing Enter retries
+func errorItem(err error, arg string) alfred.OutputItem {
+	item := alfred.OutputItem{
+		Title:    "Downloading your Todoist data...",
+		Subtitle: "Press Enter to retry",
+		Arg:      arg,
+		Icon:     &alfred.Icon{Path: "icons/loading.png"},
+	}
+
+	switch {
+	case errors.Is(err, todoist.ErrUnauthorized):
+		item.Title = "Invalid Todoist token — open settings"
+		item.Subtitle = "set your API token in the workflow configuration"
+		item.Arg = ""
+		item.Icon = &alfred.Icon{Path: "icons/Warning.png"}
+	case errors.Is(err, todoist.ErrRateLimited):
+		item.Title = "Todoist rate limit reached ⏳"
+		item.Subtitle = "wait a minute, then press Enter to retry"
+		item.Icon = &alfred.Icon{Path: "icons/Warning.png"}
+	case errors.Is(err, todoist.ErrServer):
+		item.Title = "Todoist is having trouble 🔧"
+		item.Subtitle = "the server returned an error, press Enter to retry"
+		item.Icon = &alfred.Icon{Path: "icons/Warning.png"}
+	case todoist.IsNetworkError(err):
+		item.Title = "Can't reach Todoist 📡"
+		item.Subtitle = "check your connection, then press Enter to retry"
+		item.Icon = &alfred.Icon{Path: "icons/Warning.png"}
+	}
+	return item
+}
+
+// errorMessage returns the notification text for an action that failed
+func errorMessage(err error) string {
+	switch {
+	case errors.Is(err, todoist.ErrUnauthorized):
+		return "❌ invalid token\ncheck the workflow configuration"
+	case errors.Is(err, todoist.ErrRateLimited):
+		return "⏳ rate limited\ntry again in a minute"
+	case errors.Is(err, todoist.ErrNotFound):
+		return "❌ task not found\nit may have been deleted already"
+	case errors.Is(err, todoist.ErrServer):
+		return "❌ Todoist server error\ntry again later"
+	case errors.Is(err, service.ErrRejected):
+		return "⚠️ queued changes rejected\nTodoist refused changes made offline, check debugger"
+	}
+	return "❌ server error\ncheck debugger"
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"alfredo-go/pkg/alfred"
+
+	"github.com/spf13/cobra"
+)
+
+var filtersCmd = &cobra.Command{
+	Use:   "filters [input]",
+	Short: "List the filters saved in Todoist",
+	Long: `List the filters saved in Todoist, with their task counts, filtered by input.
+Selecting one runs it as a query in filter mode.`,
+	Args:               cobra.RangeArgs(0, 1),
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		input := ""
+		if len(args) > 0 {
+			input = args[0]
+		}
+
+		output, err := taskService.Filters(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing filters: %v\n", err)
+			output = &alfred.Output{Items: []alfred.OutputItem{errorItem(err, input)}}
+		}
+
+		jsonOutput, err := output.Marshal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(jsonOutput))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(filtersCmd)
+}
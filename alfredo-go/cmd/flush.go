@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"alfredo-go/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+var flushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Replay changes queued while offline",
+	Long:  `Send task changes that were queued while Todoist was unreachable, in their original order.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := taskService.FlushQueue()
+		if errors.Is(err, service.ErrRejected) {
+			fmt.Fprintf(os.Stderr, "Error replaying queue: %v\n", err)
+			fmt.Printf("⚠️ %d queued changes synced\nsome were rejected by Todoist, check debugger\n", n)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error replaying queue: %v\n", err)
+			fmt.Println("❌ still offline\nqueued changes kept for later")
+			os.Exit(1)
+		}
+
+		if n == 0 {
+			fmt.Println("✅ nothing to sync\nno queued changes")
+			return
+		}
+		fmt.Printf("🔄 %d queued changes synced!\nAll caught up.\n", n)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(flushCmd)
+}
@@ -34,12 +34,7 @@ var parseCmd = &cobra.Command{
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing input: %v\n", err)
 			// Show friendly Alfred message instead of silent exit
-			errOutput := &alfred.Output{Items: []alfred.OutputItem{{
-				Title:    "Downloading your Todoist data...",
-				Subtitle: "Press Enter to retry",
-				Arg:      input,
-				Icon:     &alfred.Icon{Path: "icons/loading.png"},
-			}}}
+			errOutput := &alfred.Output{Items: []alfred.OutputItem{errorItem(err, input)}}
 			if errJSON, e := json.Marshal(errOutput); e == nil {
 				fmt.Println(string(errJSON))
 			}
@@ -11,7 +11,7 @@ import (
 )
 
 var queryCmd = &cobra.Command{
-	Use:   "query [today|due|all] [search]",
+	Use:   "query [today|due|all|deadline|now|upcoming|filter] [search]",
 	Short: "Query tasks with filtering and autocomplete",
 	Long: `Query tasks from Todoist with mode-based filtering and search support.
 
@@ -20,8 +20,12 @@ Modes:
   due      - Overdue tasks
   all      - All active tasks
   deadline - Tasks with deadlines, sorted by closest deadline
+  now      - Timed tasks due within the next NOW_HOURS hours (or a leading "4h" token)
+  upcoming - Tasks due over the next UPCOMING_DAYS days (or a leading "14" or "14d" token), by day
+  filter   - Tasks matching a Todoist filter query, e.g. "(today | overdue) & #Work"
 
-Search supports @label, #project, and text filtering.`,
+Search supports @label, #project, and text filtering. In filter mode the search
+is the filter query.`,
 	Args:                  cobra.RangeArgs(1, 2),
 	DisableFlagParsing:    true,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -31,8 +35,8 @@ Search supports @label, #project, and text filtering.`,
 			search = args[1]
 		}
 
-		if mode != "today" && mode != "due" && mode != "all" && mode != "deadline" {
-			fmt.Fprintf(os.Stderr, "Error: mode must be 'today', 'due', 'all', or 'deadline'\n")
+		if mode != "today" && mode != "due" && mode != "all" && mode != "deadline" && mode != "now" && mode != "upcoming" && mode != "filter" {
+			fmt.Fprintf(os.Stderr, "Error: mode must be 'today', 'due', 'all', 'deadline', 'now', 'upcoming', or 'filter'\n")
 			os.Exit(1)
 		}
 
@@ -40,12 +44,7 @@ Search supports @label, #project, and text filtering.`,
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error querying tasks: %v\n", err)
 			// Show friendly Alfred message instead of silent exit
-			errOutput := &alfred.Output{Items: []alfred.OutputItem{{
-				Title:    "Downloading your Todoist data...",
-				Subtitle: "Press Enter to retry",
-				Arg:      "",
-				Icon:     &alfred.Icon{Path: "icons/loading.png"},
-			}}}
+			errOutput := &alfred.Output{Items: []alfred.OutputItem{errorItem(err, "")}}
 			if errJSON, e := json.Marshal(errOutput); e == nil {
 				fmt.Println(string(errJSON))
 			}
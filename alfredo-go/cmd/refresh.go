@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var refreshCmd = &cobra.Command{
+	Use:    "refresh",
+	Short:  "Refresh a stale cache in the background",
+	Long:   `Replay queued changes and refresh the cached Todoist data. Started detached by queries that find the cache stale.`,
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := taskService.BackgroundRefresh(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error refreshing cache: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(refreshCmd)
+}
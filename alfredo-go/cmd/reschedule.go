@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"alfredo-go/internal/service"
+
 	"github.com/spf13/cobra"
 )
 
@@ -49,9 +52,13 @@ var rescheduleCmd = &cobra.Command{
 		}
 
 		err := taskService.RescheduleTask(taskID, dateInput)
+		if errors.Is(err, service.ErrQueued) {
+			fmt.Println("📥 offline: reschedule queued\nwill sync when Todoist is reachable")
+			return
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error rescheduling task: %v\n", err)
-			fmt.Println("❌ server error\ncheck debugger")
+			fmt.Println(errorMessage(err))
 			os.Exit(1)
 		}
 
@@ -36,7 +36,7 @@ func init() {
 // initConfig reads in config and ENV variables
 func initConfig() {
 	cfg = config.LoadConfig()
-	todoistClient = todoist.NewClient(cfg.GetToken())
+	todoistClient = todoist.NewClient(cfg.GetToken(), cfg.BaseURL)
 	dataCache = cache.NewCache(todoistClient, cfg)
 	taskService = service.NewTaskService(todoistClient, dataCache, cfg)
 }
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"alfredo-go/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the last task change",
+	Long:  `Revert the most recent complete, delete, reschedule, edit or bulk action.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		summary, warning, err := taskService.Undo()
+		if errors.Is(err, service.ErrQueued) {
+			fmt.Printf("📥 offline: %s queued\nwill sync when Todoist is reachable\n", summary)
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error undoing: %v\n", err)
+			fmt.Println(errorMessage(err))
+			os.Exit(1)
+		}
+
+		if summary == "" {
+			fmt.Println("🤷 nothing to undo\nno recent changes recorded")
+			return
+		}
+		if warning != "" {
+			fmt.Printf("↩️ %s\n⚠️ %s\n", summary, warning)
+			return
+		}
+		fmt.Printf("↩️ %s\nAs if nothing happened.\n", summary)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+}
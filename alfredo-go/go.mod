@@ -3,6 +3,7 @@ module alfredo-go
 go 1.24.0
 
 require (
+	github.com/olebedev/when v1.1.0
 	github.com/spf13/cobra v1.9.1
 	golang.org/x/text v0.33.0
 )
@@ -10,7 +11,6 @@ require (
 require (
 	github.com/AlekSi/pointer v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/olebedev/when v1.1.0 // indirect
 	github.com/pkg/errors v0.8.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 )
@@ -0,0 +1,276 @@
+// Package filter parses and evaluates Todoist's filter query language against
+// cached tasks, e.g. "(today | overdue) & #Work & !@waiting".
+//
+// Supported operators are & (and), | (or), ! (not) and parentheses. Todoist
+// shows each comma-separated query as its own list; here a comma works like |.
+package filter
+
+import (
+	"alfredo-go/pkg/todoist"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Env is what filters are evaluated against
+type Env struct {
+	Now      time.Time // in the user's timezone
+	UserID   string    // the Todoist user, for "assigned to: me"
+	Projects []todoist.Project
+	Sections []todoist.Section
+
+	projects map[string]todoist.Project // Projects by ID, built on first use
+}
+
+// projectsByID indexes the env's projects once for all the tasks it checks
+func (env *Env) projectsByID() map[string]todoist.Project {
+	if env.projects == nil {
+		env.projects = make(map[string]todoist.Project, len(env.Projects))
+		for _, p := range env.Projects {
+			env.projects[p.ID] = p
+		}
+	}
+	return env.projects
+}
+
+// Filter is a parsed filter query
+type Filter struct {
+	query string
+	root  node
+}
+
+// node is a parsed (sub)expression
+type node interface {
+	match(t *todoist.Task, env *Env) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ operand node }
+
+// predicate is a single filter term, like "today" or "@waiting"
+type predicate func(t *todoist.Task, env *Env) bool
+
+func (n andNode) match(t *todoist.Task, env *Env) bool {
+	return n.left.match(t, env) && n.right.match(t, env)
+}
+
+func (n orNode) match(t *todoist.Task, env *Env) bool {
+	return n.left.match(t, env) || n.right.match(t, env)
+}
+
+func (n notNode) match(t *todoist.Task, env *Env) bool {
+	return !n.operand.match(t, env)
+}
+
+func (p predicate) match(t *todoist.Task, env *Env) bool {
+	return p(t, env)
+}
+
+// Parse parses a filter query. lang is the language natural language dates
+// are written in, e.g. "due before: next friday".
+func Parse(query, lang string) (*Filter, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+	p := &exprParser{tokens: tokens, lang: lang}
+	root, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+	}
+	return &Filter{query: query, root: root}, nil
+}
+
+// String returns the query the filter was parsed from
+func (f *Filter) String() string {
+	return f.query
+}
+
+// Match reports whether the task matches the filter
+func (f *Filter) Match(t todoist.Task, env *Env) bool {
+	return f.root.match(&t, env)
+}
+
+// Select returns the tasks matching the filter, in their original order
+func (f *Filter) Select(tasks []todoist.Task, env *Env) []todoist.Task {
+	var result []todoist.Task
+	for _, t := range tasks {
+		if f.Match(t, env) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// --- lexer ---
+
+type tokenKind int
+
+const (
+	tokTerm tokenKind = iota
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // byte offset in the query
+}
+
+var operators = map[rune]tokenKind{
+	'&': tokAnd,
+	'|': tokOr,
+	'(': tokLParen,
+	')': tokRParen,
+	',': tokComma,
+}
+
+// lex splits a query into operators and terms. A term is the trimmed text
+// between operators, so it can contain spaces ("due before: +3 days"). ! is
+// only an operator at the start of a term; a backslash escapes an operator.
+func lex(query string) ([]token, error) {
+	var tokens []token
+	var term strings.Builder
+	termPos := -1
+
+	flush := func() {
+		if text := strings.TrimSpace(term.String()); text != "" {
+			tokens = append(tokens, token{kind: tokTerm, text: text, pos: termPos})
+		}
+		term.Reset()
+		termPos = -1
+	}
+
+	escaped := false
+	for i, r := range query {
+		op, isOp := operators[r]
+		switch {
+		case escaped:
+			term.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+			if termPos < 0 {
+				termPos = i
+			}
+		case isOp:
+			flush()
+			tokens = append(tokens, token{kind: op, text: string(r), pos: i})
+		case r == '!' && strings.TrimSpace(term.String()) == "":
+			term.Reset()
+			tokens = append(tokens, token{kind: tokNot, text: "!", pos: i})
+		default:
+			if termPos < 0 && r != ' ' {
+				termPos = i
+			}
+			term.WriteRune(r)
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("dangling \\ at the end of the filter")
+	}
+	flush()
+	return tokens, nil
+}
+
+// --- parser ---
+
+// exprParser is a recursive descent parser over the grammar
+//
+//	list  = or { "," or }
+//	or    = and { "|" and }
+//	and   = unary { "&" unary }
+//	unary = "!" unary | "(" list ")" | term
+type exprParser struct {
+	tokens []token
+	pos    int
+	lang   string
+}
+
+func (p *exprParser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *exprParser) accept(kind tokenKind) bool {
+	if tok, ok := p.peek(); ok && tok.kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseList() (node, error) {
+	return p.parseBinary(tokComma, p.parseOr, func(l, r node) node { return orNode{l, r} })
+}
+
+func (p *exprParser) parseOr() (node, error) {
+	return p.parseBinary(tokOr, p.parseAnd, func(l, r node) node { return orNode{l, r} })
+}
+
+func (p *exprParser) parseAnd() (node, error) {
+	return p.parseBinary(tokAnd, p.parseUnary, func(l, r node) node { return andNode{l, r} })
+}
+
+// parseBinary parses operands separated by op, combining them left to right
+func (p *exprParser) parseBinary(op tokenKind, operand func() (node, error), combine func(l, r node) node) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(op) {
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = combine(left, right)
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (node, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("filter ends with an operator")
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	case tokLParen:
+		inner, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(tokRParen) {
+			return nil, fmt.Errorf("missing ) for ( at position %d", tok.pos+1)
+		}
+		return inner, nil
+	case tokTerm:
+		pred, err := parseTerm(tok.text, p.lang)
+		if err != nil {
+			return nil, err
+		}
+		return pred, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+}
@@ -0,0 +1,223 @@
+package filter
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"alfredo-go/pkg/todoist"
+)
+
+// testEnv is Sunday 2026-10-18, 10:00 UTC, for user u1
+func testEnv() *Env {
+	return &Env{
+		Now:    time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC),
+		UserID: "u1",
+		Projects: []todoist.Project{
+			{ID: "p1", Name: "Work"},
+			{ID: "p2", Name: "Home"},
+			{ID: "p3", Name: "Clients", ParentID: "p1"},
+		},
+		Sections: []todoist.Section{
+			{ID: "s1", Name: "Urgent", ProjectID: "p1"},
+		},
+	}
+}
+
+var testTasks = []todoist.Task{
+	{ID: "report", Content: "Write report", ProjectID: "p1", SectionID: "s1", Priority: 4,
+		Labels: []string{"waiting"}, Due: &todoist.Due{Date: "2026-10-18"}},
+	{ID: "milk", Content: "Buy milk", Description: "oat", ProjectID: "p2", Priority: 1,
+		Due: &todoist.Due{Date: "2026-10-17"}},
+	{ID: "call", Content: "Call Acme", ProjectID: "p3", Priority: 3,
+		Due: &todoist.Due{Date: "2026-10-18T09:00:00"}, ResponsibleUID: "u1"},
+	{ID: "demo", Content: "Prepare demo", ProjectID: "p3", Priority: 2,
+		Due: &todoist.Due{Date: "2026-10-20T15:00:00"}, ResponsibleUID: "u2",
+		Deadline: &todoist.Deadline{Date: "2026-10-21"}},
+	{ID: "gym", Content: "Gym", ProjectID: "p2", Priority: 1,
+		Labels: []string{"health"}, Due: &todoist.Due{Date: "2026-10-19", IsRecurring: true, String: "every day"}},
+	{ID: "idea", Content: "Someday idea", ProjectID: "p2", Priority: 1, ParentID: "milk"},
+}
+
+func selectIDs(t *testing.T, query string) string {
+	t.Helper()
+	f, err := Parse(query, "en")
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", query, err)
+	}
+	var ids []string
+	for _, task := range f.Select(testTasks, testEnv()) {
+		ids = append(ids, task.ID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func TestFilterTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"today", "call,report"},
+		{"tomorrow", "gym"},
+		{"yesterday", "milk"},
+		{"overdue", "call,milk"},
+		{"od", "call,milk"},
+		{"no date", "idea"},
+		{"no time", "gym,milk,report"},
+		{"recurring", "gym"},
+		{"subtask", "idea"},
+		{"p1", "report"},
+		{"p4", "gym,idea,milk"},
+		{"#Work", "report"},
+		{"#work", "report"},
+		{"##Work", "call,demo,report"},
+		{"#Cli*", "call,demo"},
+		{"/Urgent", "report"},
+		{"@waiting", "report"},
+		{"@h*", "gym"},
+		{"no labels", "call,demo,idea,milk"},
+		{"2 days", "call,gym,report"},
+		{"next 3 days", "call,demo,gym,report"},
+		{"due: today", "call,report"},
+		{"date: 2026-10-20", "demo"},
+		{"due before: today", "milk"},
+		{"due before: +2 days", "call,gym,milk,report"},
+		{"due after: tomorrow", "demo"},
+		{"due before: 1 week", "call,demo,gym,milk,report"},
+		{"deadline before: +7 days", "demo"},
+		{"no deadline", "call,gym,idea,milk,report"},
+		{"assigned", "call,demo"},
+		{"assigned to: me", "call"},
+		{"assigned to: others", "demo"},
+		{"search: milk", "milk"},
+		{"search: OAT", "milk"},
+		{"view all", "call,demo,gym,idea,milk,report"},
+	}
+	for _, tt := range tests {
+		if got := selectIDs(t, tt.query); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilterOperators(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"today | overdue", "call,milk,report"},
+		{"p1 & #Work", "report"},
+		{"!@waiting & today", "call"},
+		{"today & !@waiting", "call"},
+		{"!!today & #Work", "report"},
+		{"(today | tomorrow) & ##Work", "call,report"},
+		{"today | tomorrow & #Home", "call,gym,report"},
+		{"(today | tomorrow) & #Home", "gym"},
+		{"!(today | overdue | no date)", "demo,gym"},
+		{"today, no date", "call,idea,report"},
+		{"  today   &   assigned to:   me ", "call"},
+	}
+	for _, tt := range tests {
+		if got := selectIDs(t, tt.query); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilterTimezone(t *testing.T) {
+	// Fixed 23:30 UTC on the 17th is the 18th in Rome, so due today there
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	task := todoist.Task{Due: &todoist.Due{Date: "2026-10-17T23:30:00Z", Timezone: "Europe/Rome"}}
+	f, err := Parse("today", "en")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	env := testEnv()
+	if f.Match(task, env) {
+		t.Errorf("task should not be due today in UTC")
+	}
+	env.Now = env.Now.In(rome)
+	if !f.Match(task, env) {
+		t.Errorf("task should be due today in Rome")
+	}
+}
+
+func TestFilterNaturalDates(t *testing.T) {
+	tests := []struct {
+		query, lang string
+		want        string
+	}{
+		{"due: next tuesday", "en", "demo"},
+		{"due before: next tuesday", "en", "call,gym,milk,report"},
+		{"due: domani", "it", "gym"},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.query, tt.lang)
+		if err != nil {
+			t.Fatalf("Parse(%q, %q): %v", tt.query, tt.lang, err)
+		}
+		var ids []string
+		for _, task := range f.Select(testTasks, testEnv()) {
+			ids = append(ids, task.ID)
+		}
+		sort.Strings(ids)
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%q in %s = %s, want %s", tt.query, tt.lang, got, tt.want)
+		}
+	}
+
+	// Evaluated on another day, the same filter means another date
+	f, _ := Parse("due: domani", "it")
+	env := testEnv()
+	env.Now = env.Now.AddDate(0, 0, 1)
+	if got := f.Select(testTasks, env); len(got) != 1 || got[0].ID != "demo" {
+		t.Errorf("due: domani a day later = %v, want demo", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "empty filter"},
+		{"   ", "empty filter"},
+		{"today &", "ends with an operator"},
+		{"(today | overdue", "missing )"},
+		{"today)", `unexpected ")"`},
+		{"& today", `unexpected "&"`},
+		{"banana", `unknown filter term "banana"`},
+		{"due before: the heat death", "unknown date"},
+		{"assigned to: Ann", "only supports me and others"},
+		{"#", "needs a project name"},
+		{`today \`, "dangling"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query, "en")
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want error containing %q", tt.query, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.query, err, tt.want)
+		}
+	}
+}
+
+func TestLexEscapes(t *testing.T) {
+	tasks := []todoist.Task{{ID: "rd", Labels: []string{"R&D"}}, {ID: "other", Labels: []string{"R"}}}
+	f, err := Parse(`@R\&D`, "en")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got := f.Select(tasks, testEnv())
+	if len(got) != 1 || got[0].ID != "rd" {
+		t.Errorf("got %v, want only rd", got)
+	}
+}
@@ -0,0 +1,300 @@
+package filter
+
+import (
+	"alfredo-go/internal/parser"
+	"alfredo-go/pkg/todoist"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	priorityPattern = regexp.MustCompile(`^p([1-4])$`)
+	nextDaysPattern = regexp.MustCompile(`^(?:next )?(\d+) days?$`)
+	relDatePattern  = regexp.MustCompile(`^([+-]?) ?(\d+) ?(days?|d|weeks?|w|months?|m)$`)
+	spacesPattern   = regexp.MustCompile(`\s+`)
+)
+
+// parseTerm compiles a single filter term; lang is the language of natural
+// language dates
+func parseTerm(text, lang string) (predicate, error) {
+	term := strings.ToLower(spacesPattern.ReplaceAllString(strings.TrimSpace(text), " "))
+
+	switch term {
+	case "all", "view all":
+		return func(*todoist.Task, *Env) bool { return true }, nil
+	case "today":
+		return dueOn(dateSpec{}), nil
+	case "tomorrow":
+		return dueOn(dateSpec{days: 1}), nil
+	case "yesterday":
+		return dueOn(dateSpec{days: -1}), nil
+	case "overdue", "od":
+		return overdue, nil
+	case "no date", "no due date":
+		return func(t *todoist.Task, env *Env) bool { return t.Due.Day(env.Now.Location()) == "" }, nil
+	case "no time":
+		return func(t *todoist.Task, env *Env) bool {
+			_, hasTime, ok := t.Due.In(env.Now.Location())
+			return ok && !hasTime
+		}, nil
+	case "recurring":
+		return func(t *todoist.Task, _ *Env) bool { return t.Recurring() }, nil
+	case "no deadline":
+		return func(t *todoist.Task, _ *Env) bool { return t.Deadline == nil || t.Deadline.Date == "" }, nil
+	case "no labels":
+		return func(t *todoist.Task, _ *Env) bool { return len(t.Labels) == 0 }, nil
+	case "subtask":
+		return func(t *todoist.Task, _ *Env) bool { return t.ParentID != "" }, nil
+	case "assigned":
+		return func(t *todoist.Task, _ *Env) bool { return t.ResponsibleUID != "" }, nil
+	}
+
+	if m := priorityPattern.FindStringSubmatch(term); m != nil {
+		// Todoist's p1 is the API's priority 4
+		p, _ := strconv.Atoi(m[1])
+		return func(t *todoist.Task, _ *Env) bool { return t.Priority == 5-p }, nil
+	}
+	if m := nextDaysPattern.FindStringSubmatch(term); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return func(t *todoist.Task, env *Env) bool {
+			day := t.Due.Day(env.Now.Location())
+			return day != "" && day >= env.day(dateSpec{}) && day <= env.day(dateSpec{days: n - 1})
+		}, nil
+	}
+
+	switch {
+	case strings.HasPrefix(term, "##"):
+		return projectTerm(text[strings.Index(text, "##")+2:], true)
+	case strings.HasPrefix(term, "#"):
+		return projectTerm(text[strings.Index(text, "#")+1:], false)
+	case strings.HasPrefix(term, "/"):
+		return sectionTerm(text[strings.Index(text, "/")+1:])
+	case strings.HasPrefix(term, "@"):
+		return labelTerm(text[strings.Index(text, "@")+1:])
+	}
+
+	key, value, ok := strings.Cut(term, ":")
+	if !ok {
+		return nil, fmt.Errorf("unknown filter term %q", text)
+	}
+	value = strings.TrimSpace(value)
+	switch strings.TrimSpace(key) {
+	case "due", "date":
+		return datePredicate(value, lang, dueDay, func(day, target string) bool { return day == target })
+	case "due before", "date before":
+		return datePredicate(value, lang, dueDay, func(day, target string) bool { return day < target })
+	case "due after", "date after":
+		return datePredicate(value, lang, dueDay, func(day, target string) bool { return day > target })
+	case "deadline":
+		return datePredicate(value, lang, deadlineDay, func(day, target string) bool { return day == target })
+	case "deadline before":
+		return datePredicate(value, lang, deadlineDay, func(day, target string) bool { return day < target })
+	case "deadline after":
+		return datePredicate(value, lang, deadlineDay, func(day, target string) bool { return day > target })
+	case "assigned to":
+		return assignedTo(value)
+	case "search":
+		_, original, _ := strings.Cut(text, ":")
+		return searchTerm(strings.TrimSpace(original)), nil
+	}
+	return nil, fmt.Errorf("unknown filter term %q", text)
+}
+
+// --- dates ---
+
+// dateSpec is a date in a filter: a number of days, weeks or months from
+// today, an absolute day or a natural language date
+type dateSpec struct {
+	days, months int
+	absolute     string // YYYY-MM-DD, overrides the offsets
+	natural      string // resolved against the env's now, in lang
+	lang         string
+}
+
+// day resolves a date spec to a calendar day in the env's timezone
+func (env *Env) day(d dateSpec) string {
+	if d.absolute != "" {
+		return d.absolute
+	}
+	if d.natural != "" {
+		t, _ := parser.ParseNaturalDateAt(d.natural, d.lang, env.Now)
+		return t.Format("2006-01-02")
+	}
+	return env.Now.AddDate(0, d.months, d.days).Format("2006-01-02")
+}
+
+// parseDate parses the date of a due:/deadline: term: today, tomorrow,
+// yesterday, +3 days, -1 week, 2026-10-20 or a natural language date in lang
+func parseDate(value, lang string) (dateSpec, error) {
+	switch value {
+	case "today":
+		return dateSpec{}, nil
+	case "tomorrow":
+		return dateSpec{days: 1}, nil
+	case "yesterday":
+		return dateSpec{days: -1}, nil
+	}
+	if m := relDatePattern.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		if m[1] == "-" {
+			n = -n
+		}
+		switch m[3][0] {
+		case 'w':
+			return dateSpec{days: 7 * n}, nil
+		case 'm':
+			return dateSpec{months: n}, nil
+		}
+		return dateSpec{days: n}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return dateSpec{absolute: t.Format("2006-01-02")}, nil
+	}
+	if _, ok := parser.ParseNaturalDate(value, lang); ok {
+		// Resolved when evaluated, as "next friday" depends on the day
+		return dateSpec{natural: value, lang: lang}, nil
+	}
+	return dateSpec{}, fmt.Errorf("unknown date %q", value)
+}
+
+func dueDay(t *todoist.Task, env *Env) string {
+	return t.Due.Day(env.Now.Location())
+}
+
+func deadlineDay(t *todoist.Task, _ *Env) string {
+	if t.Deadline == nil {
+		return ""
+	}
+	return t.Deadline.Date
+}
+
+// datePredicate matches tasks whose day compares to the given date; tasks
+// without that day never match
+func datePredicate(value, lang string, day func(*todoist.Task, *Env) string, cmp func(day, target string) bool) (predicate, error) {
+	spec, err := parseDate(value, lang)
+	if err != nil {
+		return nil, err
+	}
+	return func(t *todoist.Task, env *Env) bool {
+		d := day(t, env)
+		return d != "" && cmp(d, env.day(spec))
+	}, nil
+}
+
+func dueOn(spec dateSpec) predicate {
+	return func(t *todoist.Task, env *Env) bool {
+		d := dueDay(t, env)
+		return d != "" && d == env.day(spec)
+	}
+}
+
+// overdue matches tasks due before today, and timed tasks whose time has passed
+func overdue(t *todoist.Task, env *Env) bool {
+	due, hasTime, ok := t.Due.In(env.Now.Location())
+	if !ok {
+		return false
+	}
+	if hasTime {
+		return due.Before(env.Now)
+	}
+	return due.Format("2006-01-02") < env.day(dateSpec{})
+}
+
+// --- names ---
+
+// namePattern compiles a case-insensitive name match where * is a wildcard
+func namePattern(name string) (*regexp.Regexp, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("missing name")
+	}
+	parts := strings.Split(name, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.Compile("(?i)^" + strings.Join(parts, ".*") + "$")
+}
+
+// projectTerm matches tasks in the named projects, and with subprojects also
+// in their subprojects
+func projectTerm(name string, subprojects bool) (predicate, error) {
+	pattern, err := namePattern(name)
+	if err != nil {
+		return nil, fmt.Errorf("# needs a project name")
+	}
+	return func(t *todoist.Task, env *Env) bool {
+		byID := env.projectsByID()
+		// Walk up the project tree; the depth guard stops at cycles
+		id := t.ProjectID
+		for depth := 0; id != "" && depth < 16; depth++ {
+			p, ok := byID[id]
+			if !ok {
+				return false
+			}
+			if pattern.MatchString(p.Name) {
+				return true
+			}
+			if !subprojects {
+				return false
+			}
+			id = p.ParentID
+		}
+		return false
+	}, nil
+}
+
+func sectionTerm(name string) (predicate, error) {
+	pattern, err := namePattern(name)
+	if err != nil {
+		return nil, fmt.Errorf("/ needs a section name")
+	}
+	return func(t *todoist.Task, env *Env) bool {
+		for _, s := range env.Sections {
+			if s.ID == t.SectionID {
+				return pattern.MatchString(s.Name)
+			}
+		}
+		return false
+	}, nil
+}
+
+func labelTerm(name string) (predicate, error) {
+	pattern, err := namePattern(name)
+	if err != nil {
+		return nil, fmt.Errorf("@ needs a label name")
+	}
+	return func(t *todoist.Task, _ *Env) bool {
+		for _, l := range t.Labels {
+			if pattern.MatchString(l) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// assignedTo supports "me" and "others"; other collaborators aren't cached
+func assignedTo(who string) (predicate, error) {
+	switch who {
+	case "me":
+		return func(t *todoist.Task, env *Env) bool {
+			return t.ResponsibleUID != "" && t.ResponsibleUID == env.UserID
+		}, nil
+	case "others":
+		return func(t *todoist.Task, env *Env) bool {
+			return t.ResponsibleUID != "" && t.ResponsibleUID != env.UserID
+		}, nil
+	}
+	return nil, fmt.Errorf("assigned to: only supports me and others")
+}
+
+func searchTerm(text string) predicate {
+	text = strings.ToLower(text)
+	return func(t *todoist.Task, _ *Env) bool {
+		return strings.Contains(strings.ToLower(t.Content), text) ||
+			strings.Contains(strings.ToLower(t.Description), text)
+	}
+}
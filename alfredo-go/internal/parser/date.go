@@ -29,40 +29,49 @@ var (
 // Returns (resolvedDate, menuItems, needsMenu).
 // If needsMenu is true, the caller should display menuItems and exit.
 func ParseDueString(dueStr, fullInput, lang string) (string, []AutocompleteItem, bool) {
+	now := time.Now()
+	if date, ok := resolveCodedDate(dueStr, now); ok {
+		return date, nil, false
+	}
+
+	// Try natural language parsing before showing menu
+	if t, ok := ParseNaturalDateAt(dueStr, lang, now); ok {
+		return FormatResolvedDate(t), nil, false
+	}
+
+	// Could not resolve — show due date menu
+	items := BuildDueMenu(dueStr, fullInput)
+	return "", items, true
+}
+
+// resolveCodedDate resolves the coded date formats relative to now: Nd,
+// Nd with a time (7d13:30), Nw, Nm (30 days each), and ISO dates with or
+// without a time. It returns YYYY-MM-DD or YYYY-MM-DDTHH:MM.
+func resolveCodedDate(dueStr string, now time.Time) (string, bool) {
+	day := func(days int) string { return now.AddDate(0, 0, days).Format("2006-01-02") }
 	if m := relDaysPattern.FindStringSubmatch(dueStr); m != nil {
 		days, _ := strconv.Atoi(m[1])
-		return NewDate(days), nil, false
+		return day(days), true
 	}
 	if m := relDaysTimePattern.FindStringSubmatch(dueStr); m != nil {
 		days, _ := strconv.Atoi(m[1])
 		timeStr := m[2]
 		if ValidateTime(timeStr) {
-			return NewDate(days) + "T" + timeStr, nil, false
+			return day(days) + "T" + timeStr, true
 		}
 	}
 	if m := relWeeksPattern.FindStringSubmatch(dueStr); m != nil {
 		weeks, _ := strconv.Atoi(m[1])
-		return NewDate(weeks * 7), nil, false
+		return day(weeks * 7), true
 	}
 	if m := relMonthsPattern.FindStringSubmatch(dueStr); m != nil {
 		months, _ := strconv.Atoi(m[1])
-		return NewDate(months * 30), nil, false
+		return day(months * 30), true
 	}
-	if absDatePattern.MatchString(dueStr) {
-		return dueStr, nil, false
+	if absDatePattern.MatchString(dueStr) || absDateTimePattern.MatchString(dueStr) {
+		return dueStr, true
 	}
-	if absDateTimePattern.MatchString(dueStr) {
-		return dueStr, nil, false
-	}
-
-	// Try natural language parsing before showing menu
-	if t, ok := ParseNaturalDate(dueStr, lang); ok {
-		return FormatResolvedDate(t), nil, false
-	}
-
-	// Could not resolve — show due date menu
-	items := BuildDueMenu(dueStr, fullInput)
-	return "", items, true
+	return "", false
 }
 
 // BuildDueMenu builds a date picker menu for the given input
@@ -318,12 +327,18 @@ type NLPResult struct {
 // Tries English NLP (olebedev/when) first, then locale-specific keywords.
 // lang is the system language code (e.g., "it", "de", "en").
 func ParseNaturalDate(input, lang string) (time.Time, bool) {
+	return ParseNaturalDateAt(input, lang, time.Now())
+}
+
+// ParseNaturalDateAt is ParseNaturalDate relative to now instead of the
+// current time
+func ParseNaturalDateAt(input, lang string, now time.Time) (time.Time, bool) {
 	if input == "" {
 		return time.Time{}, false
 	}
 	// Try English NLP first (works for all locales since English is widely understood)
 	w := newWhenParser()
-	r, err := w.Parse(input, time.Now())
+	r, err := w.Parse(input, now)
 	if err == nil && r != nil {
 		t := r.Time
 		// If the input has no explicit time indicator, strip hours/minutes
@@ -334,7 +349,7 @@ func ParseNaturalDate(input, lang string) (time.Time, bool) {
 		return t, true
 	}
 	// Try locale-specific keywords
-	return ParseLocaleDateKeyword(input, lang)
+	return parseLocaleDateKeyword(input, lang, now)
 }
 
 // ParseNaturalDateInText finds a natural language date within a larger text.
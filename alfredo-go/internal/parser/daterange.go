@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateRange is an inclusive range of days in YYYY-MM-DD form. An empty bound
+// is open.
+type DateRange struct {
+	From string
+	To   string
+}
+
+// Contains reports whether day falls in the range. An empty day (no date)
+// never does.
+func (r DateRange) Contains(day string) bool {
+	return day != "" && (r.From == "" || day >= r.From) && (r.To == "" || day <= r.To)
+}
+
+// ResolveDateRange resolves a date filter relative to now:
+//   - a single date: today, tomorrow, yesterday, 7d, 2w, 3m, 2026-10-20 or a
+//     natural language date
+//   - a comparison with one: <7d, <=2026-10-31, >today, >=tomorrow
+//   - a range of two, either of which may be left out: 2026-10-01..2026-10-31
+//   - thisweek, nextweek, lastweek (Monday to Sunday), thismonth, nextmonth,
+//     lastmonth, or overdue
+func ResolveDateRange(spec string, now time.Time, lang string) (DateRange, bool) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	today := now.Format("2006-01-02")
+
+	switch spec {
+	case "":
+		return DateRange{}, false
+	case "overdue":
+		return DateRange{To: addDays(today, -1)}, true
+	case "thisweek", "nextweek", "lastweek":
+		// Weeks start on Monday
+		monday := now.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+		switch spec {
+		case "nextweek":
+			monday = monday.AddDate(0, 0, 7)
+		case "lastweek":
+			monday = monday.AddDate(0, 0, -7)
+		}
+		return DateRange{From: monday.Format("2006-01-02"), To: monday.AddDate(0, 0, 6).Format("2006-01-02")}, true
+	case "thismonth", "nextmonth", "lastmonth":
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		switch spec {
+		case "nextmonth":
+			first = first.AddDate(0, 1, 0)
+		case "lastmonth":
+			first = first.AddDate(0, -1, 0)
+		}
+		return DateRange{From: first.Format("2006-01-02"), To: first.AddDate(0, 1, -1).Format("2006-01-02")}, true
+	}
+
+	if from, to, ok := strings.Cut(spec, ".."); ok {
+		var r DateRange
+		if from != "" {
+			if r.From, ok = ResolveDate(from, now, lang); !ok {
+				return DateRange{}, false
+			}
+		}
+		if to != "" {
+			if r.To, ok = ResolveDate(to, now, lang); !ok {
+				return DateRange{}, false
+			}
+		}
+		return r, from != "" || to != ""
+	}
+
+	for _, op := range []string{"<=", ">=", "<", ">"} {
+		rest, ok := strings.CutPrefix(spec, op)
+		if !ok {
+			continue
+		}
+		day, ok := ResolveDate(rest, now, lang)
+		if !ok {
+			return DateRange{}, false
+		}
+		switch op {
+		case "<=":
+			return DateRange{To: day}, true
+		case ">=":
+			return DateRange{From: day}, true
+		case "<":
+			return DateRange{To: addDays(day, -1)}, true
+		}
+		return DateRange{From: addDays(day, 1)}, true
+	}
+
+	day, ok := ResolveDate(spec, now, lang)
+	return DateRange{From: day, To: day}, ok
+}
+
+// ResolveDate resolves a single date relative to now to YYYY-MM-DD: today,
+// tomorrow, yesterday, a number of days (7), the coded formats of due dates
+// (7d, 2w, 3m, 2026-10-20, see resolveCodedDate) or a natural language date
+// in lang
+func ResolveDate(expr string, now time.Time, lang string) (string, bool) {
+	expr = strings.TrimSpace(expr)
+	switch strings.ToLower(expr) {
+	case "today":
+		return now.Format("2006-01-02"), true
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format("2006-01-02"), true
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format("2006-01-02"), true
+	}
+
+	if n, err := strconv.Atoi(expr); err == nil {
+		return now.AddDate(0, 0, n).Format("2006-01-02"), true
+	}
+	if date, ok := resolveCodedDate(expr, now); ok {
+		// Only the day counts in a range
+		day, _, _ := strings.Cut(date, "T")
+		if _, err := time.Parse("2006-01-02", day); err != nil {
+			return "", false
+		}
+		return day, true
+	}
+	if t, ok := ParseNaturalDateAt(expr, lang, now); ok {
+		return t.Format("2006-01-02"), true
+	}
+	return "", false
+}
+
+// addDays shifts a YYYY-MM-DD day by n calendar days
+func addDays(day string, n int) string {
+	t, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return day
+	}
+	return t.AddDate(0, 0, n).Format("2006-01-02")
+}
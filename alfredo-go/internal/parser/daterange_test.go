@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveDateRange(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 10, 21, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		from, to string
+	}{
+		{"today", "2026-10-21", "2026-10-21"},
+		{"tomorrow", "2026-10-22", "2026-10-22"},
+		{"3", "2026-10-24", "2026-10-24"},
+		{"2026-10-01", "2026-10-01", "2026-10-01"},
+		{"<7d", "", "2026-10-27"},
+		{"<=7d", "", "2026-10-28"},
+		{">today", "2026-10-22", ""},
+		{">=1w", "2026-10-28", ""},
+		{"<1m", "", "2026-11-19"},
+		{"3m", "2027-01-19", "2027-01-19"},
+		{"friday", "2026-10-23", "2026-10-23"},
+		{"2026-10-01..2026-10-31", "2026-10-01", "2026-10-31"},
+		{"today..2w", "2026-10-21", "2026-11-04"},
+		{"..2026-10-31", "", "2026-10-31"},
+		{"2026-10-01..", "2026-10-01", ""},
+		{"thisweek", "2026-10-19", "2026-10-25"},
+		{"nextweek", "2026-10-26", "2026-11-01"},
+		{"lastweek", "2026-10-12", "2026-10-18"},
+		{"thismonth", "2026-10-01", "2026-10-31"},
+		{"nextmonth", "2026-11-01", "2026-11-30"},
+		{"overdue", "", "2026-10-20"},
+		{"ThisWeek", "2026-10-19", "2026-10-25"},
+	}
+	for _, tt := range tests {
+		r, ok := ResolveDateRange(tt.spec, now, "en")
+		if !ok {
+			t.Errorf("ResolveDateRange(%q) failed", tt.spec)
+			continue
+		}
+		if r.From != tt.from || r.To != tt.to {
+			t.Errorf("ResolveDateRange(%q) = %s..%s, want %s..%s", tt.spec, r.From, r.To, tt.from, tt.to)
+		}
+	}
+
+	for _, spec := range []string{"", "..", "<", "2026-13-01", "soonish..", "<banana"} {
+		if r, ok := ResolveDateRange(spec, now, "en"); ok {
+			t.Errorf("ResolveDateRange(%q) = %+v, want failure", spec, r)
+		}
+	}
+}
+
+func TestResolveDateRangeSundayWeek(t *testing.T) {
+	// On a Sunday, this week is the one that started six days earlier
+	now := time.Date(2026, 10, 25, 9, 0, 0, 0, time.UTC)
+	r, ok := ResolveDateRange("thisweek", now, "en")
+	if !ok || r.From != "2026-10-19" || r.To != "2026-10-25" {
+		t.Errorf("thisweek on Sunday = %+v, want 2026-10-19..2026-10-25", r)
+	}
+}
+
+func TestDateRangeContains(t *testing.T) {
+	r := DateRange{From: "2026-10-01", To: "2026-10-31"}
+	tests := []struct {
+		day  string
+		want bool
+	}{
+		{"2026-10-01", true},
+		{"2026-10-31", true},
+		{"2026-09-30", false},
+		{"2026-11-01", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := r.Contains(tt.day); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.day, got, tt.want)
+		}
+	}
+	if !(DateRange{To: "2026-10-20"}).Contains("2020-01-01") {
+		t.Errorf("open start should contain any earlier day")
+	}
+}
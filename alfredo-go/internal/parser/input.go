@@ -10,11 +10,17 @@ import (
 	"golang.org/x/text/unicode/norm"
 )
 
-var inputPattern = regexp.MustCompile(`\s*([@#]\([^)]+\)|\S+)\s*`)
+// inputPattern matches a token: a word, or @/#/^ followed by a name in
+// parentheses, optionally negated with - and joined to alternatives with |
+var inputPattern = regexp.MustCompile(`\s*(-?(?:[@#^]\([^)]+\)|[^\s|]+)(?:\|(?:[@#^]\([^)]+\)|[^\s|]+))*|\S+)\s*`)
 var deadlinePattern = regexp.MustCompile(`\{([^}]+)\}`)
 
+// descriptionPattern matches "//" at the start of the input or after
+// whitespace (so URLs like https://... are left alone) and everything after it
+var descriptionPattern = regexp.MustCompile(`(?s)(?:^|\s)//(.*)$`)
+
 // ParseInput tokenizes user input, keeping together elements with spaces if they are
-// in parentheses and preceded by # or @
+// in parentheses and preceded by #, @ or ^ (also as in -@(a b) or @(a b)|@c)
 func ParseInput(input string) []string {
 	matches := inputPattern.FindAllStringSubmatch(input, -1)
 	result := make([]string, 0, len(matches))
@@ -39,6 +45,10 @@ type ParsedTask struct {
 	DeadlineRaw  string // raw deadline text for NLP (e.g., "friday")
 	Priority     int    // Todoist API priority (4=highest, 1=lowest)
 	PrioString   string
+	Description  string // text after "//"
+	DescGiven    bool   // "//" was given, even with nothing after it
+	ParentID     string // task to create this one under, set with ^parent
+	ParentName   string // includes ^ prefix
 	RawInput     string
 }
 
@@ -50,6 +60,13 @@ type InputContext struct {
 	ProjectCounts map[string]int  // project name (no prefix) -> count
 	PartialMatch  bool
 	Lang          string          // system language code (e.g., "it", "de", "en")
+	Tasks         []TaskRef       // candidate parents for ^parent
+}
+
+// TaskRef identifies a cached task by ID and content
+type TaskRef struct {
+	ID      string
+	Content string
 }
 
 // ParseNewTaskInput parses raw input for new task creation.
@@ -58,12 +75,20 @@ type InputContext struct {
 func ParseNewTaskInput(input string, ctx *InputContext) (*ParsedTask, []AutocompleteItem, bool) {
 	lang := ctx.Lang
 
+	// Extract the // description first; it may contain anything
+	var description string
+	cleanedInput := input
+	loc := descriptionPattern.FindStringSubmatchIndex(input)
+	if loc != nil {
+		description = strings.TrimSpace(input[loc[2]:loc[3]])
+		cleanedInput = strings.TrimSpace(input[:loc[0]])
+	}
+
 	// Extract {deadline} before tokenizing
 	var deadlineRaw string
-	cleanedInput := input
-	if m := deadlinePattern.FindStringSubmatch(input); m != nil {
+	if m := deadlinePattern.FindStringSubmatch(cleanedInput); m != nil {
 		deadlineRaw = strings.TrimSpace(m[1])
-		cleanedInput = deadlinePattern.ReplaceAllString(input, "")
+		cleanedInput = deadlinePattern.ReplaceAllString(cleanedInput, "")
 		cleanedInput = strings.TrimSpace(cleanedInput)
 		// collapse double spaces
 		for strings.Contains(cleanedInput, "  ") {
@@ -75,8 +100,10 @@ func ParseNewTaskInput(input string, ctx *InputContext) (*ParsedTask, []Autocomp
 	utils.Log("input elements: %v", elements)
 
 	parsed := &ParsedTask{
-		Priority: 1,
-		RawInput: input,
+		Priority:    1,
+		Description: description,
+		DescGiven:   loc != nil,
+		RawInput:    input,
 	}
 
 	// Resolve deadline
@@ -181,6 +208,51 @@ func ParseNewTaskInput(input string, ctx *InputContext) (*ParsedTask, []Autocomp
 				return nil, items, true
 			}
 
+		} else if strings.HasPrefix(item, "^") && len(item) > 1 {
+			// Handle parent task
+			item = unwrapParens(item, "^")
+
+			if id := findTaskRef(ctx.Tasks, item[1:]); id != "" {
+				parsed.ParentID = id
+				parsed.ParentName = item
+			} else {
+				// Autocomplete from cached task contents
+				var candidates []string
+				for _, t := range ctx.Tasks {
+					candidates = append(candidates, "^"+t.Content)
+				}
+				subset := filterMatch(candidates, item, "^", true)
+				remaining := removeElement(elements, elements[i])
+				remainingStr := strings.Join(remaining, " ")
+
+				if len(subset) > 0 {
+					items := make([]AutocompleteItem, 0, len(subset))
+					for _, parent := range subset {
+						parentStr := formatWithParens(parent, "^")
+						var arg string
+						if remainingStr != "" {
+							arg = remainingStr + " " + parentStr + " "
+						} else {
+							arg = parentStr + " "
+						}
+						items = append(items, AutocompleteItem{
+							Title:    "subtask of: " + parent[1:],
+							Subtitle: arg,
+							Arg:      arg,
+							Icon:     "icons/bullet.png",
+						})
+					}
+					return nil, items, true
+				}
+				items := []AutocompleteItem{{
+					Title:    "no tasks matching",
+					Subtitle: "try another query?",
+					Arg:      "",
+					Icon:     "icons/Warning.png",
+				}}
+				return nil, items, true
+			}
+
 		} else if strings.EqualFold(item, "p1") || strings.EqualFold(item, "p2") ||
 			strings.EqualFold(item, "p3") || strings.EqualFold(item, "p4") {
 			switch strings.ToLower(item) {
@@ -261,6 +333,18 @@ type AutocompleteItem struct {
 	Variables map[string]any
 }
 
+// findTaskRef returns the ID of the first task whose content matches,
+// ignoring case and Unicode normalization
+func findTaskRef(tasks []TaskRef, content string) string {
+	content = NormalizeUnicode(content)
+	for _, t := range tasks {
+		if strings.EqualFold(NormalizeUnicode(t.Content), content) {
+			return t.ID
+		}
+	}
+	return ""
+}
+
 func unwrapParens(item, prefix string) string {
 	if strings.HasPrefix(item, prefix+"(") && strings.HasSuffix(item, ")") && strings.Contains(item, " ") {
 		item = strings.Replace(item, "(", "", 1)
@@ -62,6 +62,16 @@ func TestParseInput(t *testing.T) {
 			input:    "buy milk p1 due:7d",
 			expected: []string{"buy", "milk", "p1", "due:7d"},
 		},
+		{
+			name:     "negated label and project with spaces",
+			input:    "-@(my label) -#(My Project) -word",
+			expected: []string{"-@(my label)", "-#(My Project)", "-word"},
+		},
+		{
+			name:     "alternatives",
+			input:    "@home|@(run errands) a|b",
+			expected: []string{"@home|@(run errands)", "a|b"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -163,6 +173,71 @@ func TestParseNewTaskInput(t *testing.T) {
 	}
 }
 
+func TestParseNewTaskInputDescription(t *testing.T) {
+	ctx := &InputContext{
+		AllLabels:     []string{"@work"},
+		AllProjects:   []string{"#Work"},
+		LabelCounts:   map[string]int{"work": 1},
+		ProjectCounts: map[string]int{"Work": 1},
+		PartialMatch:  true,
+		Lang:          "en",
+	}
+
+	tests := []struct {
+		input       string
+		content     string
+		description string
+	}{
+		{"call Bob @work // ask about {friday} and #budget", "call Bob", "ask about {friday} and #budget"},
+		{"//just a note", "", "just a note"},
+		{"read https://example.com/a // from Ann", "read https://example.com/a", "from Ann"},
+		{"read https://example.com/a", "read https://example.com/a", ""},
+		{"call Bob //", "call Bob", ""},
+	}
+	for _, tt := range tests {
+		parsed, autocomplete, needsExit := ParseNewTaskInput(tt.input, ctx)
+		if needsExit {
+			t.Fatalf("ParseNewTaskInput(%q): unexpected autocomplete %v", tt.input, autocomplete)
+		}
+		if parsed.Content != tt.content {
+			t.Errorf("ParseNewTaskInput(%q).Content = %q, want %q", tt.input, parsed.Content, tt.content)
+		}
+		if parsed.Description != tt.description {
+			t.Errorf("ParseNewTaskInput(%q).Description = %q, want %q", tt.input, parsed.Description, tt.description)
+		}
+		if parsed.Deadline != "" {
+			t.Errorf("ParseNewTaskInput(%q): braces in the description set a deadline", tt.input)
+		}
+	}
+}
+
+func TestParseNewTaskInputParent(t *testing.T) {
+	ctx := &InputContext{
+		PartialMatch: true,
+		Lang:         "en",
+		Tasks: []TaskRef{
+			{ID: "1", Content: "Plan trip"},
+			{ID: "2", Content: "pack"},
+		},
+	}
+
+	parsed, _, needsExit := ParseNewTaskInput("book hotel ^(plan trip)", ctx)
+	if needsExit {
+		t.Fatal("expected a known parent to resolve")
+	}
+	if parsed.ParentID != "1" || parsed.Content != "book hotel" {
+		t.Errorf("ParentID = %q, Content = %q; want 1, book hotel", parsed.ParentID, parsed.Content)
+	}
+
+	_, items, needsExit := ParseNewTaskInput("book hotel ^pla", ctx)
+	if !needsExit || len(items) != 1 {
+		t.Fatalf("expected one autocomplete item, got %v", items)
+	}
+	if items[0].Arg != "book hotel ^(Plan trip) " {
+		t.Errorf("Arg = %q, want %q", items[0].Arg, "book hotel ^(Plan trip) ")
+	}
+}
+
 func TestParseNewTaskInputDeadline(t *testing.T) {
 	ctx := &InputContext{
 		AllLabels:     []string{},
@@ -277,6 +277,10 @@ var localeNextPrefixes = map[string][]string{
 // lang is a 2-letter language code (e.g., "it", "de").
 // Returns the resolved time and true if the keyword was recognized.
 func ParseLocaleDateKeyword(input, lang string) (time.Time, bool) {
+	return parseLocaleDateKeyword(input, lang, time.Now())
+}
+
+func parseLocaleDateKeyword(input, lang string, now time.Time) (time.Time, bool) {
 	if lang == "" || lang == "en" {
 		return time.Time{}, false // English handled by olebedev/when
 	}
@@ -309,7 +313,6 @@ func ParseLocaleDateKeyword(input, lang string) (time.Time, bool) {
 		return time.Time{}, false
 	}
 
-	now := time.Now()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	if kw.isWeekday {
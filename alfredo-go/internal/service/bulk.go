@@ -0,0 +1,282 @@
+package service
+
+import (
+	"alfredo-go/internal/parser"
+	"alfredo-go/pkg/alfred"
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bulk actions are encoded as "<action>" or "<action>:<value>", e.g.
+// "complete", "reschedule:1d", "move:#Work/Urgent", "label:waiting", "priority:4".
+
+// matchingTasks returns the tasks a query in mode with the given search input
+// would list. An @label, #project or !filter that doesn't resolve selects
+// nothing, so a typo can't widen the action to the whole mode. Search words
+// must appear as typed: a fuzzy match is fine for finding a task, not for
+// picking the ones to complete or delete.
+func (s *TaskService) matchingTasks(mode, input string) ([]todoist.Task, error) {
+	if err := s.ensureFresh(mode); err != nil {
+		return nil, fmt.Errorf("failed to load data: %w", err)
+	}
+	data := s.cache.Data()
+	now := time.Now().In(s.location())
+
+	if mode == "filter" {
+		return selectFiltered(data, input, now, s.cfg.DueLang)
+	}
+	span, _, rest := s.modeSpan(mode, input)
+	toShow, _ := selectTasks(data, mode, now, span)
+	q := parseQuery(data, toShow, rest, now, s.cfg.DueLang)
+	if q.labelFrag != "" || q.projFrag != "" || q.filterFrag != "" {
+		return nil, nil
+	}
+	q.exact = true
+	return q.apply(toShow), nil
+}
+
+// BulkMenu lists the actions that can be applied to every task matching mode
+// and input. actionInput picks the action: empty for the default menu,
+// #project to move, @label to add a label, p1-p4 to set priority, or a date
+// (as in the reschedule menu) to reschedule.
+func (s *TaskService) BulkMenu(mode, input, actionInput string) (*alfred.Output, error) {
+	tasks, err := s.matchingTasks(mode, input)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &alfred.Output{Items: []alfred.OutputItem{}}
+	if len(tasks) == 0 {
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    "no tasks matching your query 🙁",
+			Subtitle: "nothing to apply bulk actions to",
+			Arg:      "",
+			Icon:     &alfred.Icon{Path: "icons/Warning.png"},
+		})
+		return output, nil
+	}
+
+	scope := fmt.Sprintf("%d %s in %s", len(tasks), pluralize(len(tasks), "task", "tasks"), mode)
+	if input != "" {
+		scope += " matching '" + strings.TrimSpace(input) + "'"
+	}
+	add := func(title, arg, icon string) {
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    title,
+			Subtitle: scope,
+			Arg:      arg,
+			Icon:     &alfred.Icon{Path: icon},
+		})
+	}
+
+	data := s.cache.Data()
+	actionInput = parser.NormalizeUnicode(actionInput)
+	lower := strings.ToLower(actionInput)
+
+	switch {
+	case actionInput == "":
+		add(fmt.Sprintf("✅ Complete all %d", len(tasks)), "complete", "icons/done.png")
+		for _, item := range parser.BuildRescheduleMenu("", "", s.cfg.DueLang) {
+			add(item.Title, "reschedule:"+item.Arg, item.Icon)
+		}
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    "type #project to move, @label to add a label, p1-p4 to set priority",
+			Subtitle: "or a date to reschedule (e.g. 3d, 2w, 2026-10-20, friday)",
+			Arg:      "",
+			Icon:     &alfred.Icon{Path: "icons/bullet.png"},
+		})
+
+	case strings.HasPrefix(actionInput, "#"):
+		frag := strings.ToLower(unwrapParens(actionInput, "#")[1:])
+		for _, name := range projectPaths(data.Projects, data.Sections) {
+			if strings.Contains(strings.ToLower(name), frag) {
+				add("📋 Move to #"+name, "move:#"+name, "icons/project.png")
+			}
+		}
+
+	case strings.HasPrefix(actionInput, "@"):
+		frag := unwrapParens(actionInput, "@")[1:]
+		exact := false
+		for _, l := range data.Labels {
+			if strings.Contains(strings.ToLower(l.Name), strings.ToLower(frag)) {
+				add("🏷️ Add @"+l.Name, "label:"+l.Name, "icons/label.png")
+				exact = exact || l.Name == frag
+			}
+		}
+		if !exact && frag != "" {
+			add("🏷️ Add new label @"+frag, "label:"+frag, "icons/newLabel.png")
+		}
+
+	case lower == "p1" || lower == "p2" || lower == "p3" || lower == "p4":
+		level, _ := strconv.Atoi(lower[1:])
+		add(fmt.Sprintf("Set priority to %s", lower), fmt.Sprintf("priority:%d", 5-level), "icons/bullet.png")
+
+	case lower == "done" || lower == "complete":
+		add(fmt.Sprintf("✅ Complete all %d", len(tasks)), "complete", "icons/done.png")
+
+	default:
+		for _, item := range parser.BuildRescheduleMenu(actionInput, "", s.cfg.DueLang) {
+			arg := ""
+			if item.Arg != "" {
+				arg = "reschedule:" + item.Arg
+			}
+			add(item.Title, arg, item.Icon)
+		}
+	}
+
+	if len(output.Items) == 0 {
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    "no matching action",
+			Subtitle: "try another query?",
+			Arg:      "",
+			Icon:     &alfred.Icon{Path: "icons/Warning.png"},
+		})
+	}
+	return output, nil
+}
+
+// BulkApply applies an encoded action to every task matching mode and input
+// in a single batched request and returns a summary for the notification
+func (s *TaskService) BulkApply(mode, input, action string) (string, error) {
+	tasks, err := s.matchingTasks(mode, input)
+	if err != nil {
+		return "", err
+	}
+	if len(tasks) == 0 {
+		return "no tasks matching, nothing changed", nil
+	}
+
+	name, value, _ := strings.Cut(action, ":")
+	var cmds []todoist.Command
+	var prior []todoist.Task // tasks as they were, for undo
+	var done string
+	add := func(t todoist.Task, cmd todoist.Command) {
+		cmds = append(cmds, cmd)
+		prior = append(prior, t)
+	}
+
+	switch name {
+	case "complete":
+		for _, t := range tasks {
+			add(t, todoist.ItemClose(t.ID))
+		}
+		done = "completed"
+
+	case "reschedule":
+		newDate := parser.ResolveRescheduleDate(value)
+		for _, t := range tasks {
+			add(t, todoist.ItemUpdate(t.ID, map[string]any{"due": t.Reschedule(newDate)}))
+		}
+		done = "rescheduled to " + newDate
+
+	case "move":
+		idx := s.cache.Data().Index()
+		projectName, sectionName, _ := strings.Cut(strings.TrimPrefix(value, "#"), "/")
+		projectID := idx.ProjectID(projectName)
+		sectionID := ""
+		if sectionName != "" {
+			sectionID = idx.SectionID(value)
+		}
+		if projectID == "" || (sectionName != "" && sectionID == "") {
+			return "", fmt.Errorf("unknown project %q", value)
+		}
+		for _, t := range tasks {
+			add(t, todoist.ItemMove(t.ID, projectID, sectionID))
+		}
+		done = "moved to " + value
+
+	case "label":
+		for _, t := range tasks {
+			if containsLabel(t.Labels, value) {
+				continue
+			}
+			labels := append(append([]string{}, t.Labels...), value)
+			add(t, todoist.ItemUpdate(t.ID, map[string]any{"labels": labels}))
+		}
+		done = "labelled @" + value
+
+	case "priority":
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 1 || priority > 4 {
+			return "", fmt.Errorf("invalid priority %q", value)
+		}
+		for _, t := range tasks {
+			add(t, todoist.ItemUpdate(t.ID, map[string]any{"priority": priority}))
+		}
+		done = fmt.Sprintf("set to p%d", 5-priority)
+
+	default:
+		return "", fmt.Errorf("unknown bulk action %q", action)
+	}
+
+	if len(cmds) == 0 {
+		return "nothing to change", nil
+	}
+
+	utils.Log("bulk %s on %d tasks", name, len(cmds))
+	failed := 0
+	err = s.submit(cmds, func() error {
+		resp, err := s.client.ExecuteCommands(cmds)
+		if err != nil && len(resp.SyncStatus) == 0 {
+			return err
+		}
+		// A request failing part way leaves the commands after it unsent
+		if err != nil {
+			utils.Log("warning: bulk %s stopped part way: %v", name, err)
+		}
+		for _, cmd := range cmds {
+			if err := resp.Err(cmd.UUID); err != nil {
+				utils.Log("warning: bulk %s failed: %v", cmd.Type, err)
+				failed++
+			}
+		}
+		return nil
+	})
+	if err := s.journal(name, prior, err); err != nil {
+		return "", err
+	}
+
+	summary := fmt.Sprintf("%d %s %s", len(cmds)-failed, pluralize(len(cmds)-failed, "task", "tasks"), done)
+	if failed > 0 {
+		summary += fmt.Sprintf(" (%d failed)", failed)
+	}
+	return summary, nil
+}
+
+// projectPaths lists active projects and their sections as "Project" and
+// "Project/Section"
+func projectPaths(projects []todoist.Project, sections []todoist.Section) []string {
+	var paths []string
+	for _, p := range projects {
+		if p.IsDeleted || p.IsArchived {
+			continue
+		}
+		paths = append(paths, p.Name)
+		for _, sect := range sections {
+			if sect.ProjectID == p.ID {
+				paths = append(paths, p.Name+"/"+sect.Name)
+			}
+		}
+	}
+	return paths
+}
+
+func containsLabel(labels []string, name string) bool {
+	for _, l := range labels {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
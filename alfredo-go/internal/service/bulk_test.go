@@ -0,0 +1,131 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestBulkApply_Priority(t *testing.T) {
+	s, srv := newTestService(t)
+
+	summary, err := s.BulkApply("today", "", "priority:4")
+	if err != nil {
+		t.Fatalf("BulkApply: %v", err)
+	}
+	if summary != "2 tasks set to p1" {
+		t.Errorf("summary = %q", summary)
+	}
+	for _, task := range srv.ActiveTasks() {
+		if task.Due != nil && task.Priority != 4 {
+			t.Errorf("task %s priority = %d, want 4", task.ID, task.Priority)
+		}
+	}
+}
+
+func TestBulkApply_CompleteMatchingSearch(t *testing.T) {
+	s, srv := newTestService(t)
+
+	summary, err := s.BulkApply("today", "milk", "complete")
+	if err != nil {
+		t.Fatalf("BulkApply: %v", err)
+	}
+	if summary != "1 task completed" {
+		t.Errorf("summary = %q", summary)
+	}
+	if task, _ := srv.Task("t2"); !task.Checked {
+		t.Error("t2 should be completed")
+	}
+	if task, _ := srv.Task("t1"); task.Checked {
+		t.Error("t1 doesn't match the search and should stay open")
+	}
+}
+
+func TestBulkApply_NoFuzzyMatches(t *testing.T) {
+	s, srv := newTestService(t)
+
+	// The search finds "write report" fuzzily, but a bulk action needs the
+	// words as typed
+	out, err := s.QueryTasks("today", "rprt")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if len(out.Items) == 0 || out.Items[0].Variables["myTaskID"] != "t1" {
+		t.Fatalf("search rprt = %+v, want t1 first", out.Items)
+	}
+	summary, err := s.BulkApply("today", "rprt", "complete")
+	if err != nil {
+		t.Fatalf("BulkApply: %v", err)
+	}
+	if summary != "no tasks matching, nothing changed" {
+		t.Errorf("summary = %q", summary)
+	}
+	if task, _ := srv.Task("t1"); task.Checked {
+		t.Error("t1 should stay open")
+	}
+}
+
+func TestBulkApply_UnresolvedFragmentsSelectNothing(t *testing.T) {
+	s, srv := newTestService(t)
+
+	for _, input := range []string{"#Wrok @waitng", "#Wrok", "@waitng", "!nofilter"} {
+		summary, err := s.BulkApply("today", input, "complete")
+		if err != nil {
+			t.Fatalf("BulkApply(%q): %v", input, err)
+		}
+		if summary != "no tasks matching, nothing changed" {
+			t.Errorf("BulkApply(%q) summary = %q", input, summary)
+		}
+		out, err := s.BulkMenu("today", input, "")
+		if err != nil {
+			t.Fatalf("BulkMenu(%q): %v", input, err)
+		}
+		if len(out.Items) != 1 || out.Items[0].Arg != "" {
+			t.Errorf("BulkMenu(%q) items = %+v, want the no tasks item", input, out.Items)
+		}
+	}
+	if len(srv.ActiveTasks()) != 3 {
+		t.Errorf("active tasks = %d, want all 3 left open", len(srv.ActiveTasks()))
+	}
+}
+
+func TestBulkApply_MoveAndLabel(t *testing.T) {
+	s, srv := newTestService(t)
+
+	if _, err := s.BulkApply("today", "#Work", "move:#Home"); err != nil {
+		t.Fatalf("BulkApply move: %v", err)
+	}
+	if task, _ := srv.Task("t1"); task.ProjectID != "p2" {
+		t.Errorf("t1 is in %s, want p2", task.ProjectID)
+	}
+	if _, err := s.BulkApply("today", "", "move:#Nowhere"); err == nil {
+		t.Error("moving to an unknown project should fail")
+	}
+
+	summary, err := s.BulkApply("today", "", "label:waiting")
+	if err != nil {
+		t.Fatalf("BulkApply label: %v", err)
+	}
+	// t1 already has the label
+	if summary != "1 task labelled @waiting" {
+		t.Errorf("summary = %q", summary)
+	}
+	if task, _ := srv.Task("t2"); len(task.Labels) != 1 || task.Labels[0] != "waiting" {
+		t.Errorf("t2 labels = %v, want [waiting]", task.Labels)
+	}
+}
+
+func TestBulkMenu(t *testing.T) {
+	s, _ := newTestService(t)
+
+	out, err := s.BulkMenu("today", "", "")
+	if err != nil {
+		t.Fatalf("BulkMenu: %v", err)
+	}
+	if first := out.Items[0]; first.Arg != "complete" || first.Subtitle != "2 tasks in today" {
+		t.Errorf("first item = %q (%s) / %q", first.Title, first.Arg, first.Subtitle)
+	}
+
+	out, _ = s.BulkMenu("today", "", "#Ho")
+	if len(out.Items) != 1 || out.Items[0].Arg != "move:#Home" {
+		t.Errorf("#Ho items = %+v, want a move to Home", out.Items)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"alfredo-go/pkg/alfred"
+	"alfredo-go/pkg/todoist"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Comments lists the comments on a task, newest first, keeping those that
+// contain every word of input. Non-empty input is also offered as a new
+// comment.
+func (s *TaskService) Comments(taskID, input string) (*alfred.Output, error) {
+	if err := s.ensureFresh(""); err != nil {
+		return nil, fmt.Errorf("failed to load data: %w", err)
+	}
+	data := s.cache.Data()
+	input = strings.TrimSpace(input)
+
+	output := &alfred.Output{Items: []alfred.OutputItem{}}
+	task, ok := s.cache.Task(taskID)
+	if !ok {
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    "task not found",
+			Subtitle: "it may have been completed or deleted",
+			Arg:      "",
+			Icon:     &alfred.Icon{Path: "icons/Warning.png"},
+		})
+		return output, nil
+	}
+
+	if input != "" {
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    "💬 Add comment: " + input,
+			Subtitle: "on '" + task.Content + "'",
+			Arg:      input,
+			Variables: map[string]any{
+				"myTaskID": taskID,
+			},
+			Icon: &alfred.Icon{Path: "icons/newTask.png"},
+		})
+	}
+
+	notes := taskNotes(data.Notes, taskID)
+	search := strings.Fields(strings.ToLower(input))
+	for _, n := range notes {
+		if !matchNote(n, search) {
+			continue
+		}
+		title, _, _ := strings.Cut(n.Content, "\n")
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    title,
+			Subtitle: "💬 " + formatPostedAt(n.PostedAt) + " · ⌘L to read, ⌘C to copy",
+			Arg:      "",
+			Icon:     &alfred.Icon{Path: "icons/bullet.png"},
+			Text:     &alfred.Text{Copy: n.Content, LargeType: n.Content},
+		})
+	}
+
+	if len(output.Items) == 0 {
+		title := "no comments on '" + task.Content + "'"
+		if len(notes) > 0 {
+			title = "no comments matching"
+		}
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    title,
+			Subtitle: "type to add one",
+			Arg:      "",
+			Icon:     &alfred.Icon{Path: "icons/Warning.png"},
+		})
+	}
+	return output, nil
+}
+
+// AddComment adds a comment to a task
+func (s *TaskService) AddComment(taskID, content string) error {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return errors.New("empty comment")
+	}
+	cmds := []todoist.Command{todoist.NoteAdd(todoist.NewUUID(), taskID, content)}
+	return s.submit(cmds, func() error {
+		return s.sendCommands(cmds)
+	})
+}
+
+// taskNotes returns the comments on a task, newest first
+func taskNotes(notes []todoist.Note, taskID string) []todoist.Note {
+	var result []todoist.Note
+	for _, n := range notes {
+		if n.ItemID == taskID {
+			result = append(result, n)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].PostedAt > result[j].PostedAt
+	})
+	return result
+}
+
+func matchNote(n todoist.Note, search []string) bool {
+	content := strings.ToLower(n.Content)
+	for _, word := range search {
+		if !strings.Contains(content, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatPostedAt shows a comment timestamp in local time
+func formatPostedAt(postedAt string) string {
+	t, err := time.Parse(time.RFC3339, postedAt)
+	if err != nil {
+		return postedAt
+	}
+	return t.Local().Format("Jan 2, 2006 15:04")
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"alfredo-go/pkg/alfred"
+	"alfredo-go/pkg/cache"
+	"alfredo-go/pkg/todoist"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filters lists the filters saved in Todoist whose name matches input, each
+// with the number of tasks it currently matches. Selecting one runs it in the
+// filter query mode.
+func (s *TaskService) Filters(input string) (*alfred.Output, error) {
+	if err := s.ensureFresh("filter"); err != nil {
+		return nil, fmt.Errorf("failed to load data: %w", err)
+	}
+	data := s.cache.Data()
+	now := time.Now().In(s.location())
+
+	output := &alfred.Output{Items: s.savedFilterItems(data, now, strings.TrimSpace(input), true)}
+	if s.cache.RefreshRunning() {
+		output.Rerun = refreshRerun
+	}
+	if len(output.Items) == 0 {
+		title := "no saved filters matching"
+		if len(data.Filters) == 0 {
+			title = "no saved filters"
+		}
+		output.Items = append(output.Items, alfred.OutputItem{
+			Title:    title,
+			Subtitle: "filters saved in Todoist show up here after a refresh",
+			Arg:      "",
+			Icon:     &alfred.Icon{Path: "icons/Warning.png"},
+		})
+	}
+	return output, nil
+}
+
+// savedFilterItems lists the saved filters whose name matches fragment,
+// favorites first and then in Todoist's order. With count, each filter is
+// evaluated to show how many tasks it matches.
+func (s *TaskService) savedFilterItems(data *cache.CachedData, now time.Time, fragment string, count bool) []alfred.OutputItem {
+	filters := make([]todoist.Filter, len(data.Filters))
+	copy(filters, data.Filters)
+	sort.SliceStable(filters, func(i, j int) bool {
+		if filters[i].IsFavorite != filters[j].IsFavorite {
+			return filters[i].IsFavorite
+		}
+		return filters[i].ItemOrder < filters[j].ItemOrder
+	})
+
+	fragment = strings.ToLower(fragment)
+	items := []alfred.OutputItem{}
+	for _, f := range filters {
+		name := strings.ToLower(f.Name)
+		if s.cfg.PartialMatch && !strings.Contains(name, fragment) ||
+			!s.cfg.PartialMatch && !strings.HasPrefix(name, fragment) {
+			continue
+		}
+
+		title := f.Name
+		if f.IsFavorite {
+			title = "⭐ " + title
+		}
+		subtitle := f.Query
+		if count {
+			if tasks, err := selectFiltered(data, f.Query, now, s.cfg.DueLang); err != nil {
+				title += " (?)"
+				subtitle = "⚠️ " + err.Error() + ": " + f.Query
+			} else {
+				title += fmt.Sprintf(" (%d)", len(tasks))
+			}
+		}
+
+		items = append(items, alfred.OutputItem{
+			Title:    title,
+			Subtitle: subtitle,
+			Arg:      "",
+			Variables: map[string]any{
+				"myIter": true,
+				"myArg":  f.Query,
+				"myMode": "filter",
+			},
+			Icon: &alfred.Icon{Path: "icons/bullet.png"},
+		})
+	}
+	return items
+}
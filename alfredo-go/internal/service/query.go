@@ -0,0 +1,589 @@
+package service
+
+import (
+	"alfredo-go/internal/filter"
+	"alfredo-go/internal/parser"
+	"alfredo-go/pkg/alfred"
+	"alfredo-go/pkg/cache"
+	"alfredo-go/pkg/config"
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/utils"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryFilter holds the filters parsed from the search input of a query
+type queryFilter struct {
+	include    [][]queryTerm // each group needs a matching term: @a|@b is one group
+	exclude    []queryTerm   // -@label, -#project and -word: none may match
+	level      string        // "sub" or "top" from is:sub / is:top
+	parentID   string        // from parent:<id>, lists that task's subtasks
+	finalInput []string      // input tokens minus the fragment being autocompleted
+	labelFrag  string        // incomplete @label being typed, if any
+	projFrag   string        // incomplete #project being typed, if any
+	fragPrefix string        // what precedes the fragment in its token, like - or @a|
+	filterFrag string        // !name of a saved filter being typed, if any
+	exact      bool          // search words match as typed rather than fuzzily
+}
+
+// queryTerm is a single @label, #project[/section], search word, or a
+// priority or date token checked by pred
+type queryTerm struct {
+	label     string
+	projectID string
+	sectionID string
+	word      string
+	ids       map[string]bool // tasks matching word, if the index could tell
+	pred      func(t todoist.Task) bool
+}
+
+func (qt queryTerm) match(t todoist.Task) bool {
+	switch {
+	case qt.pred != nil:
+		return qt.pred(t)
+	case qt.label != "":
+		for _, l := range t.Labels {
+			if l == qt.label {
+				return true
+			}
+		}
+		return false
+	case qt.sectionID != "":
+		return t.SectionID == qt.sectionID
+	case qt.projectID != "":
+		return t.ProjectID == qt.projectID
+	case qt.ids != nil:
+		return qt.ids[t.ID]
+	}
+	return searchScore(t, qt.word) > 0
+}
+
+// searchScore scores a search word against a task: fuzzily on the content
+// and, a little lower, the description. desc: words only match the
+// description, as typed.
+func searchScore(t todoist.Task, word string) float64 {
+	if strings.HasPrefix(strings.ToLower(word), "desc:") {
+		if matchSearch(t, []string{word}) {
+			return 1
+		}
+		return 0
+	}
+	return max(utils.FuzzyScore(word, t.Content), 0.8*utils.FuzzyScore(word, t.Description))
+}
+
+// selectTasks returns the tasks shown in the given mode, sorted for display,
+// along with the mode's icon. Due dates are compared by calendar day in now's
+// location. span is the look-ahead of the now mode in hours, and of the
+// upcoming mode in days.
+func selectTasks(data *cache.CachedData, mode string, now time.Time, span int) ([]todoist.Task, string) {
+	var toShow []todoist.Task
+	var icon string
+	loc := now.Location()
+	today := now.Format("2006-01-02")
+
+	switch mode {
+	case "today":
+		seen := map[string]bool{}
+		for _, t := range data.Tasks {
+			if t.Due != nil && t.Due.Day(loc) == today {
+				toShow = append(toShow, t)
+				seen[t.ID] = true
+			}
+		}
+		for _, t := range data.Tasks {
+			if !seen[t.ID] && t.Deadline != nil && t.Deadline.Date == today {
+				toShow = append(toShow, t)
+			}
+		}
+		sortByDue(toShow, loc, "")
+		icon = "icons/today.png"
+
+	case "due":
+		seen := map[string]bool{}
+		for _, t := range data.Tasks {
+			if day := t.Due.Day(loc); day != "" && day < today {
+				toShow = append(toShow, t)
+				seen[t.ID] = true
+			}
+		}
+		for _, t := range data.Tasks {
+			if !seen[t.ID] && t.Deadline != nil && t.Deadline.Date < today {
+				toShow = append(toShow, t)
+			}
+		}
+		sortByDue(toShow, loc, "")
+		icon = "icons/overdue.png"
+
+	case "now":
+		// Timed tasks from earlier today up to span hours ahead
+		until := now.Add(time.Duration(span) * time.Hour)
+		for _, t := range data.Tasks {
+			due, hasTime, ok := t.Due.In(loc)
+			if ok && hasTime && !due.After(until) && due.Format("2006-01-02") >= today {
+				toShow = append(toShow, t)
+			}
+		}
+		sortByDue(toShow, loc, "")
+		icon = "icons/today.png"
+
+	case "upcoming":
+		// Today and the following span-1 days
+		last := now.AddDate(0, 0, span-1).Format("2006-01-02")
+		for _, t := range data.Tasks {
+			if day := t.Due.Day(loc); day >= today && day <= last {
+				toShow = append(toShow, t)
+			}
+		}
+		sortByDue(toShow, loc, "")
+		icon = "icons/today.png"
+
+	case "all":
+		toShow = make([]todoist.Task, len(data.Tasks))
+		copy(toShow, data.Tasks)
+		sortByDue(toShow, loc, "9999-12-31")
+		icon = "icons/bullet.png"
+
+	case "deadline":
+		for _, t := range data.Tasks {
+			if t.Deadline != nil && t.Deadline.Date != "" {
+				toShow = append(toShow, t)
+			}
+		}
+		sort.Slice(toShow, func(i, j int) bool {
+			return toShow[i].Deadline.Date < toShow[j].Deadline.Date
+		})
+		icon = "icons/deadline.png"
+	}
+
+	return toShow, icon
+}
+
+// selectFiltered returns the tasks matching a Todoist filter query, sorted
+// like the all mode. lang is the language of natural language dates in it.
+func selectFiltered(data *cache.CachedData, query string, now time.Time, lang string) ([]todoist.Task, error) {
+	f, err := filter.Parse(query, lang)
+	if err != nil {
+		return nil, err
+	}
+	env := &filter.Env{Now: now, Projects: data.Projects, Sections: data.Sections}
+	if data.User != nil {
+		env.UserID = data.User.ID
+	}
+	tasks := f.Select(data.Tasks, env)
+	sortByDue(tasks, now.Location(), "9999-12-31")
+	return tasks, nil
+}
+
+// filterErrorOutput explains why a filter query can't be evaluated
+func filterErrorOutput(query string, err error) *alfred.Output {
+	title, subtitle := "invalid filter: "+err.Error(), query
+	if strings.TrimSpace(query) == "" {
+		title, subtitle = "type a Todoist filter", "e.g. (today | overdue) & #Work & !@waiting"
+	}
+	return &alfred.Output{Items: []alfred.OutputItem{{
+		Title:    title,
+		Subtitle: subtitle,
+		Arg:      "",
+		Icon:     &alfred.Icon{Path: "icons/Warning.png"},
+	}}}
+}
+
+// sortByDue orders tasks by due date and time in loc, full-day tasks first
+// within a day. Tasks without a due date sort as if due on missing.
+func sortByDue(tasks []todoist.Task, loc *time.Location, missing string) {
+	key := func(t todoist.Task) string {
+		due, hasTime, ok := t.Due.In(loc)
+		switch {
+		case !ok:
+			return missing
+		case hasTime:
+			return due.Format("2006-01-02T15:04")
+		}
+		return due.Format("2006-01-02")
+	}
+	// Parsing dates in every comparison would dominate large lists
+	keys := make(map[string]string, len(tasks))
+	for _, t := range tasks {
+		keys[t.ID] = key(t)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return keys[tasks[i].ID] < keys[tasks[j].ID]
+	})
+}
+
+// Leading look-ahead tokens: hours in now mode ("4h"), days in upcoming mode
+// ("14" or "14d", as in "upcoming 7").
+var (
+	hoursSpanPattern = regexp.MustCompile(`^(\d+)h$`)
+	daysSpanPattern  = regexp.MustCompile(`^(\d+)d?$`)
+)
+
+// modeSpan returns the look-ahead of modes that have one: NOW_HOURS hours for
+// the now mode and UPCOMING_DAYS days for the upcoming mode, unless input
+// starts with a look-ahead token. It also returns that token and the input
+// without it.
+func (s *TaskService) modeSpan(mode, input string) (span int, token, rest string) {
+	var pattern *regexp.Regexp
+	switch mode {
+	case "now":
+		span, pattern = s.cfg.NowHours, hoursSpanPattern
+		if span <= 0 {
+			span = config.DefaultNowHours
+		}
+	case "upcoming":
+		span, pattern = s.cfg.UpcomingDays, daysSpanPattern
+		if span <= 0 {
+			span = config.DefaultUpcomingDays
+		}
+	default:
+		return 0, "", input
+	}
+
+	fields := strings.Fields(input)
+	if len(fields) > 0 {
+		if m := pattern.FindStringSubmatch(fields[0]); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+				rest = strings.TrimPrefix(strings.TrimLeft(input, " "), fields[0])
+				return n, fields[0], strings.TrimLeft(rest, " ")
+			}
+		}
+	}
+	return span, "", input
+}
+
+// describeDue says when a task is due relative to now. Timed tasks due today,
+// or overdue by less than a day, are described by the clock: "at 14:30",
+// "in 45 min", "2h overdue".
+func describeDue(due *todoist.Due, now time.Time) string {
+	t, hasTime, ok := due.In(now.Location())
+	if !ok {
+		return ""
+	}
+	days := todoist.DaysBetween(t.Format("2006-01-02"), now.Format("2006-01-02"))
+
+	if hasTime {
+		diff := t.Sub(now)
+		switch {
+		case diff >= 0 && diff < time.Hour:
+			if m := int(diff.Round(time.Minute).Minutes()); m > 0 {
+				return fmt.Sprintf("in %d min ⏰", m)
+			}
+			return "now ⏰"
+		case diff >= 0 && days == 0:
+			return "at " + t.Format("15:04")
+		case diff < 0 && diff > -time.Hour:
+			return fmt.Sprintf("%d min overdue❗", int(-diff.Minutes()))
+		case diff < 0 && diff > -24*time.Hour:
+			return fmt.Sprintf("%dh overdue❗", int(-diff.Hours()))
+		}
+	}
+
+	dayWord := "days"
+	if abs(days) == 1 {
+		dayWord = "day"
+	}
+	at := ""
+	if hasTime {
+		at = " at " + t.Format("15:04")
+	}
+	switch {
+	case days == 0:
+		return "DUE TODAY"
+	case days < 0:
+		return fmt.Sprintf("due in %d %s%s ⚠️", abs(days), dayWord, at)
+	default:
+		return fmt.Sprintf("%d %s overdue❗", days, dayWord)
+	}
+}
+
+// parseQuery tokenizes search input into label, project/section and text
+// filters. A leading - excludes instead, and | joins alternatives. Labels and
+// projects are only accepted if they occur in toShow; anything else is
+// treated as a fragment to autocomplete. lang is the language of natural
+// language dates in due: and deadline: tokens.
+func parseQuery(data *cache.CachedData, toShow []todoist.Task, input string, now time.Time, lang string) *queryFilter {
+	// Get counts from subset
+	_, labelList := cache.FetchLabelsFromSubset(toShow)
+	_, projectList := cache.FetchProjectsFromSubset(toShow, data.Projects, data.Sections)
+	labelsAll, projectsAll := newNameSet(labelList), newNameSet(projectList)
+	idx := data.Index()
+
+	inputItems := parser.ParseInput(input)
+	q := &queryFilter{finalInput: make([]string, len(inputItems))}
+	copy(q.finalInput, inputItems)
+
+	for i, rawItem := range inputItems {
+		item := parser.NormalizeUnicode(rawItem)
+		lower := strings.ToLower(item)
+
+		switch {
+		case i == 0 && strings.HasPrefix(item, "!"):
+			// Saved filters are picked rather than combined with the search,
+			// so they're only offered for a query starting with one
+			q.filterFrag = item
+			q.finalInput = removeElement(q.finalInput, rawItem)
+			continue
+		case lower == "is:sub" || lower == "is:top":
+			q.level = lower[3:]
+			continue
+		case strings.HasPrefix(lower, "parent:") && len(item) > len("parent:"):
+			q.parentID = item[len("parent:"):]
+			continue
+		}
+
+		body, negated := item, false
+		if len(item) > 1 && item[0] == '-' {
+			body, negated = item[1:], true
+		}
+
+		var terms []queryTerm
+		complete := true
+		offset := len(item) - len(body)
+		for _, alt := range splitAlternatives(body, labelsAll, projectsAll) {
+			prefix := item[:offset]
+			offset += len(alt) + 1
+
+			if strings.HasPrefix(alt, "@") {
+				cleaned := unwrapParens(alt, "@")
+				if !labelsAll.has(cleaned) {
+					q.labelFrag, q.fragPrefix, complete = cleaned, prefix, false
+					break
+				}
+				terms = append(terms, queryTerm{label: cleaned[1:]})
+
+			} else if strings.HasPrefix(alt, "#") {
+				cleaned := unwrapParens(alt, "#")
+				if !projectsAll.has(cleaned) {
+					q.projFrag, q.fragPrefix, complete = cleaned, prefix, false
+					break
+				}
+				if strings.Contains(cleaned, "/") {
+					terms = append(terms, queryTerm{sectionID: idx.SectionID(cleaned)})
+				} else {
+					terms = append(terms, queryTerm{projectID: idx.ProjectID(cleaned[1:])})
+				}
+
+			} else if term, ok := tokenTerm(alt, now, lang); ok {
+				terms = append(terms, term)
+
+			} else {
+				ids, _ := idx.Search(alt)
+				terms = append(terms, queryTerm{word: alt, ids: ids})
+			}
+		}
+
+		switch {
+		case !complete:
+			q.finalInput = removeElement(q.finalInput, rawItem)
+		case negated:
+			q.exclude = append(q.exclude, terms...)
+		default:
+			q.include = append(q.include, terms)
+		}
+	}
+
+	return q
+}
+
+var priorityTokenPattern = regexp.MustCompile(`^p(<=|>=|<|>)?([1-4])$`)
+
+// tokenTerm recognizes priority and date tokens: p1-p4 and comparisons like
+// p<3 (p1 and p2), nodue, nodeadline, and due:/deadline: followed by a date
+// range as understood by parser.ResolveDateRange. Dates are compared by day
+// in now's location.
+func tokenTerm(token string, now time.Time, lang string) (queryTerm, bool) {
+	lower := strings.ToLower(token)
+
+	if m := priorityTokenPattern.FindStringSubmatch(lower); m != nil {
+		// Todoist's p1 is the API's priority 4
+		n, _ := strconv.Atoi(m[2])
+		op := m[1]
+		return queryTerm{pred: func(t todoist.Task) bool {
+			p := 5 - t.Priority
+			switch op {
+			case "<":
+				return p < n
+			case "<=":
+				return p <= n
+			case ">":
+				return p > n
+			case ">=":
+				return p >= n
+			}
+			return p == n
+		}}, true
+	}
+
+	switch lower {
+	case "nodue":
+		return queryTerm{pred: func(t todoist.Task) bool { return t.Due == nil }}, true
+	case "nodeadline":
+		return queryTerm{pred: func(t todoist.Task) bool { return t.Deadline == nil || t.Deadline.Date == "" }}, true
+	}
+
+	for _, field := range []string{"due:", "deadline:"} {
+		spec, ok := strings.CutPrefix(lower, field)
+		if !ok {
+			continue
+		}
+		r, ok := parser.ResolveDateRange(spec, now, lang)
+		if !ok {
+			return queryTerm{}, false
+		}
+		if field == "due:" {
+			return queryTerm{pred: func(t todoist.Task) bool {
+				return r.Contains(t.Due.Day(now.Location()))
+			}}, true
+		}
+		return queryTerm{pred: func(t todoist.Task) bool {
+			return t.Deadline != nil && r.Contains(t.Deadline.Date)
+		}}, true
+	}
+	return queryTerm{}, false
+}
+
+// splitAlternatives splits a token at each | that starts another @label or
+// #project (or at every | in plain words), unless the whole token is a known
+// label or project whose name contains |
+func splitAlternatives(body string, labels, projects nameSet) []string {
+	if !strings.Contains(body, "|") ||
+		labels.has(unwrapParens(body, "@")) || projects.has(unwrapParens(body, "#")) {
+		return []string{body}
+	}
+	if !strings.HasPrefix(body, "@") && !strings.HasPrefix(body, "#") {
+		return strings.Split(body, "|")
+	}
+
+	var alts []string
+	start := 0
+	for i := 1; i < len(body)-1; i++ {
+		if body[i] == '|' && (body[i+1] == '@' || body[i+1] == '#') {
+			alts = append(alts, body[start:i])
+			start = i + 1
+		}
+	}
+	return append(alts, body[start:])
+}
+
+// nameSet holds label or project names in Unicode normal form
+type nameSet map[string]bool
+
+func newNameSet(names []string) nameSet {
+	set := make(nameSet, len(names))
+	for _, n := range names {
+		set[parser.NormalizeUnicode(n)] = true
+	}
+	return set
+}
+
+// has reports whether name is in the set, in any Unicode normal form
+func (ns nameSet) has(name string) bool {
+	return ns[parser.NormalizeUnicode(name)]
+}
+
+// apply returns the tasks matching every filter in the query. The subtasks
+// of a parent: filter are listed in their Todoist order.
+func (q *queryFilter) apply(tasks []todoist.Task) []todoist.Task {
+	if !q.filtered() {
+		return tasks
+	}
+
+	var result []todoist.Task
+	for _, t := range tasks {
+		switch {
+		case q.level == "sub" && t.ParentID == "",
+			q.level == "top" && t.ParentID != "",
+			q.parentID != "" && t.ParentID != q.parentID,
+			!q.matches(t):
+			continue
+		}
+		result = append(result, t)
+	}
+	if q.parentID != "" {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].ChildOrder < result[j].ChildOrder
+		})
+	}
+	return result
+}
+
+// matches reports whether a task has a match in every include group and none
+// among the exclusions
+func (q *queryFilter) matches(t todoist.Task) bool {
+	for _, group := range q.include {
+		found := false
+		for _, term := range group {
+			if q.exact && term.word != "" && matchSearch(t, []string{term.word}) ||
+				(!q.exact || term.word == "") && term.match(t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, term := range q.exclude {
+		// Excluded words must appear as typed; fuzzy matches would drop too much
+		if term.word != "" && matchSearch(t, []string{term.word}) ||
+			term.word == "" && term.match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// rank orders tasks by how well they match the query's search words,
+// blended with their priority and how close their due date is. Without
+// search words the order is left alone.
+func (q *queryFilter) rank(tasks []todoist.Task, now time.Time) {
+	var groups [][]string
+	for _, group := range q.include {
+		var words []string
+		for _, term := range group {
+			if term.word != "" {
+				words = append(words, term.word)
+			}
+		}
+		if len(words) > 0 {
+			groups = append(groups, words)
+		}
+	}
+	if len(groups) == 0 {
+		return
+	}
+
+	today := now.Format("2006-01-02")
+	scores := make(map[string]float64, len(tasks))
+	for _, t := range tasks {
+		// The best alternative counts for each group
+		match := 0.0
+		for _, words := range groups {
+			best := 0.0
+			for _, w := range words {
+				best = max(best, searchScore(t, w))
+			}
+			match += best
+		}
+		match /= float64(len(groups))
+
+		priority := float64(t.Priority-1) / 3
+		due := 0.0
+		if day := t.Due.Day(now.Location()); day != "" {
+			due = 1 / float64(1+abs(todoist.DaysBetween(today, day)))
+		}
+		scores[t.ID] = 0.6*match + 0.25*priority + 0.15*due
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return scores[tasks[i].ID] > scores[tasks[j].ID]
+	})
+}
+
+// filtered reports whether the query narrows down the tasks of a mode
+func (q *queryFilter) filtered() bool {
+	return len(q.include) > 0 || len(q.exclude) > 0 || q.level != "" || q.parentID != ""
+}
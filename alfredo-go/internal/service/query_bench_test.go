@@ -0,0 +1,56 @@
+package service
+
+import (
+	"alfredo-go/pkg/cache"
+	"alfredo-go/pkg/config"
+	"alfredo-go/pkg/todoist/todoisttest"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newBenchService serves queries from a cache of n generated tasks. Every
+// query loads the cache from disk, like each run of the workflow does.
+func newBenchService(b *testing.B, n int) *TaskService {
+	b.Helper()
+	f := todoisttest.NewFixture(n, time.Now())
+	data := cache.CachedData{
+		Tasks:     f.Tasks,
+		Projects:  f.Projects,
+		Sections:  f.Sections,
+		Labels:    f.Labels,
+		Notes:     f.Notes,
+		FetchedAt: time.Now(),
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cfg := &config.Config{DataFolder: b.TempDir(), RefreshRate: 24 * time.Hour, DueLang: "en"}
+	if err := os.WriteFile(filepath.Join(cfg.DataFolder, "allData.json"), raw, 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	s := NewTaskService(nil, cache.NewCache(nil, cfg), cfg)
+	s.startRefresh = func() error { return errors.New("no background refresh in benchmarks") }
+	return s
+}
+
+func benchmarkQuery(b *testing.B, mode, input string) {
+	s := newBenchService(b, 10000)
+	b.ResetTimer()
+	for range b.N {
+		if _, err := s.QueryTasks(mode, input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryTasks_All(b *testing.B)     { benchmarkQuery(b, "all", "") }
+func BenchmarkQueryTasks_Today(b *testing.B)   { benchmarkQuery(b, "today", "") }
+func BenchmarkQueryTasks_Search(b *testing.B)  { benchmarkQuery(b, "all", "reprot ") }
+func BenchmarkQueryTasks_Project(b *testing.B) { benchmarkQuery(b, "all", "#(Project 7) ") }
+func BenchmarkQueryTasks_Label(b *testing.B)   { benchmarkQuery(b, "all", "@label3 meeting ") }
@@ -7,17 +7,29 @@ import (
 	"alfredo-go/pkg/config"
 	"alfredo-go/pkg/todoist"
 	"alfredo-go/pkg/utils"
+	"errors"
 	"fmt"
-	"sort"
 	"strings"
 	"time"
 )
 
+// ErrQueued is returned by mutations that could not reach Todoist. The change
+// has been queued for replay and already applied to the local cache.
+var ErrQueued = errors.New("todoist unreachable, change queued")
+
+// ErrRejected is returned by FlushQueue, wrapping the reason for each, when
+// Todoist rejected queued changes. They are dropped from the queue since they
+// would be rejected again.
+var ErrRejected = errors.New("queued changes rejected by todoist")
+
 // TaskService handles task-related operations
 type TaskService struct {
 	client *todoist.Client
 	cache  *cache.Cache
 	cfg    *config.Config
+
+	// startRefresh refreshes a stale cache in the background
+	startRefresh func() error
 }
 
 // NewTaskService creates a new TaskService
@@ -26,19 +38,22 @@ func NewTaskService(client *todoist.Client, c *cache.Cache, cfg *config.Config)
 		client: client,
 		cache:  c,
 		cfg:    cfg,
+		startRefresh: func() error {
+			_, err := c.StartBackgroundRefresh("refresh")
+			return err
+		},
 	}
 }
 
 // QueryTasks is the main query function, porting alfredo-query.py logic
 func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
-	if err := s.cache.EnsureFresh(); err != nil {
+	if err := s.ensureFresh(mode); err != nil {
 		return nil, fmt.Errorf("failed to load data: %w", err)
 	}
 
 	data := s.cache.Data()
-	now := time.Now()
+	now := time.Now().In(s.location())
 	today := now.Format("2006-01-02")
-	todayDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 
 	// Build goals string
 	goalsString := ""
@@ -72,165 +87,56 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 			soFarCompleted, dailyGoal, statusDay, totalWeekCompleted, weeklyGoal, statusWeek)
 	}
 
-	// Subset tasks based on mode
-	var toShow []todoist.Task
-	var icon string
-
-	switch mode {
-	case "today":
-		seen := map[string]bool{}
-		for _, t := range data.Tasks {
-			if t.Due != nil && strings.Split(t.Due.Date, "T")[0] == today {
-				toShow = append(toShow, t)
-				seen[t.ID] = true
-			}
-		}
-		for _, t := range data.Tasks {
-			if !seen[t.ID] && t.Deadline != nil && t.Deadline.Date == today {
-				toShow = append(toShow, t)
-			}
-		}
-		sort.Slice(toShow, func(i, j int) bool {
-			di := ""
-			if toShow[i].Due != nil {
-				di = toShow[i].Due.Date
-			}
-			dj := ""
-			if toShow[j].Due != nil {
-				dj = toShow[j].Due.Date
-			}
-			return di < dj
-		})
-		icon = "icons/today.png"
-
-	case "due":
-		seen := map[string]bool{}
-		for _, t := range data.Tasks {
-			if t.Due != nil && t.Due.Date < today {
-				toShow = append(toShow, t)
-				seen[t.ID] = true
-			}
-		}
-		for _, t := range data.Tasks {
-			if !seen[t.ID] && t.Deadline != nil && t.Deadline.Date < today {
-				toShow = append(toShow, t)
-			}
+	// How fresh the data is, shown next to the goals
+	refreshing := s.cache.RefreshRunning()
+	if status := syncStatus(data.FetchedAt, now, refreshing); status != "" {
+		if goalsString == "" {
+			goalsString = " "
 		}
-		sort.Slice(toShow, func(i, j int) bool {
-			di := ""
-			if toShow[i].Due != nil {
-				di = toShow[i].Due.Date
-			}
-			dj := ""
-			if toShow[j].Due != nil {
-				dj = toShow[j].Due.Date
-			}
-			return di < dj
-		})
-		icon = "icons/overdue.png"
-
-	case "all":
-		toShow = make([]todoist.Task, len(data.Tasks))
-		copy(toShow, data.Tasks)
-		sort.Slice(toShow, func(i, j int) bool {
-			di := "9999-12-31"
-			dj := "9999-12-31"
-			if toShow[i].Due != nil {
-				di = toShow[i].Due.Date
-			}
-			if toShow[j].Due != nil {
-				dj = toShow[j].Due.Date
-			}
-			return di < dj
-		})
-		icon = "icons/bullet.png"
+		goalsString += status + " "
+	}
 
-	case "deadline":
-		for _, t := range data.Tasks {
-			if t.Deadline != nil && t.Deadline.Date != "" {
-				toShow = append(toShow, t)
-			}
+	// Subset tasks based on mode
+	span, spanToken, rest := s.modeSpan(mode, input)
+	toShow, icon := selectTasks(data, mode, now, span)
+	if mode == "filter" {
+		// The input is a Todoist filter rather than a search
+		var err error
+		if toShow, err = selectFiltered(data, input, now, s.cfg.DueLang); err != nil {
+			return filterErrorOutput(input, err), nil
 		}
-		sort.Slice(toShow, func(i, j int) bool {
-			return toShow[i].Deadline.Date < toShow[j].Deadline.Date
-		})
-		icon = "icons/deadline.png"
+		icon, rest = "icons/bullet.png", ""
 	}
 
-	// Get counts from subset
-	_, labelsAll := cache.FetchLabelsFromSubset(toShow)
-	_, projectsAll := cache.FetchProjectsFromSubset(toShow, data.Projects, data.Sections)
-
-	// Parse input
-	inputItems := parser.ParseInput(input)
-	var filterLabels, filterProjects, filterSections, searchStrings []string
-	finalInput := make([]string, len(inputItems))
-	copy(finalInput, inputItems)
-
-	labelFlag := false
-	projectFlag := false
-	var tagFrag, projFrag string
+	// Parse input, keeping a span token in front of autocompleted input
+	q := parseQuery(data, toShow, rest, now, s.cfg.DueLang)
+	myInput := strings.TrimSpace(spanToken + " " + strings.Join(q.finalInput, " "))
 
 	output := &alfred.Output{Items: []alfred.OutputItem{}}
-
-	for _, item := range inputItems {
-		item = parser.NormalizeUnicode(item)
-
-		if strings.HasPrefix(item, "@") {
-			// Unwrap parentheses
-			cleaned := unwrapParens(item, "@")
-
-			if containsStr(labelsAll, cleaned) {
-				filterLabels = append(filterLabels, cleaned[1:])
-			} else {
-				labelFlag = true
-				tagFrag = cleaned
-				finalInput = removeElement(finalInput, item)
-			}
-
-		} else if strings.HasPrefix(item, "#") {
-			cleaned := unwrapParens(item, "#")
-
-			if containsStr(projectsAll, cleaned) {
-				if strings.Contains(cleaned, "/") {
-					parts := strings.SplitN(cleaned, "/", 2)
-					projID := getProjectID(data.Projects, parts[0][1:])
-					sectID := getSectionID(data.Projects, data.Sections, cleaned)
-					filterProjects = append(filterProjects, projID)
-					filterSections = append(filterSections, sectID)
-				} else {
-					projID := getProjectID(data.Projects, cleaned[1:])
-					filterProjects = append(filterProjects, projID)
-				}
-			} else {
-				projectFlag = true
-				projFrag = cleaned
-				finalInput = removeElement(finalInput, item)
-			}
-
-		} else {
-			searchStrings = append(searchStrings, item)
-		}
+	if refreshing {
+		output.Rerun = refreshRerun
 	}
 
-	myInput := strings.Join(finalInput, " ")
-
-	// Apply filters
-	toShow = filterTasks(toShow, filterLabels, filterProjects, filterSections, searchStrings)
+	// Apply filters, ranking by relevance when there is free text (the
+	// upcoming agenda stays in day order)
+	toShow = q.apply(toShow)
+	if mode != "upcoming" {
+		q.rank(toShow, now)
+	}
 
 	// Label autocomplete
-	if labelFlag {
+	if q.labelFrag != "" {
 		labelCounts, labels := cache.FetchLabelsFromSubset(toShow)
 		var subset []string
 		if s.cfg.PartialMatch {
 			for _, l := range labels {
-				if strings.Contains(strings.ToLower(l), strings.ToLower(tagFrag[1:])) {
+				if strings.Contains(strings.ToLower(l), strings.ToLower(q.labelFrag[1:])) {
 					subset = append(subset, l)
 				}
 			}
 		} else {
 			for _, l := range labels {
-				if strings.Contains(strings.ToLower(l), strings.ToLower(tagFrag)) {
+				if strings.Contains(strings.ToLower(l), strings.ToLower(q.labelFrag)) {
 					subset = append(subset, l)
 				}
 			}
@@ -238,7 +144,7 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 
 		if len(subset) > 0 {
 			for _, label := range subset {
-				labelStr := formatWithParens(label, "@")
+				labelStr := q.fragPrefix + formatWithParens(label, "@")
 				var arg string
 				if myInput != "" {
 					arg = myInput + " " + labelStr + " "
@@ -274,18 +180,18 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 	}
 
 	// Project autocomplete
-	if projectFlag {
+	if q.projFrag != "" {
 		projectCounts, projectList := cache.FetchProjectsFromSubset(toShow, data.Projects, data.Sections)
 		var subset []string
 		if s.cfg.PartialMatch {
 			for _, p := range projectList {
-				if strings.Contains(strings.ToLower(p), strings.ToLower(projFrag[1:])) {
+				if strings.Contains(strings.ToLower(p), strings.ToLower(q.projFrag[1:])) {
 					subset = append(subset, p)
 				}
 			}
 		} else {
 			for _, p := range projectList {
-				if strings.Contains(strings.ToLower(p), strings.ToLower(projFrag)) {
+				if strings.Contains(strings.ToLower(p), strings.ToLower(q.projFrag)) {
 					subset = append(subset, p)
 				}
 			}
@@ -293,7 +199,7 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 
 		if len(subset) > 0 {
 			for _, proj := range subset {
-				projStr := formatWithParens(proj, "#")
+				projStr := q.fragPrefix + formatWithParens(proj, "#")
 				var arg string
 				if myInput != "" {
 					arg = myInput + " " + projStr + " "
@@ -328,36 +234,62 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 		return output, nil
 	}
 
+	// Saved filter autocomplete: picking one switches to the filter mode
+	if q.filterFrag != "" {
+		// Counted only once picked, not on every keystroke of the name
+		output.Items = s.savedFilterItems(data, now, q.filterFrag[1:], false)
+		if len(output.Items) == 0 {
+			output.Items = append(output.Items, alfred.OutputItem{
+				Title:    "no saved filters matching",
+				Subtitle: "try another query?",
+				Arg:      "",
+				Variables: map[string]any{
+					"myIter": true,
+					"myArg":  myInput + " ",
+					"myMode": mode,
+				},
+				Icon: &alfred.Icon{Path: "icons/Warning.png"},
+			})
+		}
+		return output, nil
+	}
+
 	// Build task output
 	if len(toShow) > 0 {
 		matchCount := len(toShow)
 		countR := 1
 
+		// Subtask and comment counts come from all tasks, so they don't depend on the mode
+		idx := data.Index()
+		// The upcoming agenda starts each day with a separator
+		dayCounts := map[string]int{}
+		if mode == "upcoming" {
+			for _, t := range toShow {
+				dayCounts[t.Due.Day(now.Location())]++
+			}
+		}
+		lastDay := ""
+
 		for _, task := range toShow {
-			dueString := ""
-			if task.Due != nil {
-				dueDate := parseDueDate(task.Due.Date)
-				if !dueDate.IsZero() {
-					dueDays := int(todayDate.Sub(dueDate).Hours() / 24)
-					dayWord := "days"
-					if abs(dueDays) == 1 {
-						dayWord = "day"
-					}
-					if dueDays == 0 {
-						dueString = "DUE TODAY"
-					} else if dueDays < 0 {
-						dueString = fmt.Sprintf("due in %d %s ⚠️", abs(dueDays), dayWord)
-					} else {
-						dueString = fmt.Sprintf("%d %s overdue❗", dueDays, dayWord)
-					}
+			if mode == "upcoming" {
+				if day := task.Due.Day(now.Location()); day != lastDay {
+					output.Items = append(output.Items, daySeparator(day, dayCounts[day], today, input, mode))
+					lastDay = day
+				}
+			}
+			dueString := describeDue(task.Due, now)
+			subtitleRecurrence := ""
+			if task.Recurring() {
+				dueString += " 🔁"
+				if task.Due != nil && task.Due.String != "" {
+					subtitleRecurrence = " 🔁 " + task.Due.String
 				}
 			}
 
 			deadlineString := ""
 			if task.Deadline != nil && task.Deadline.Date != "" {
-				dlDate := parseDueDate(task.Deadline.Date)
-				if !dlDate.IsZero() {
-					dlDays := int(todayDate.Sub(dlDate).Hours() / 24)
+				if _, err := time.Parse("2006-01-02", task.Deadline.Date); err == nil {
+					dlDays := todoist.DaysBetween(task.Deadline.Date, today)
 					dayWord := "days"
 					if abs(dlDays) == 1 {
 						dayWord = "day"
@@ -377,7 +309,7 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 				labelsString = "🏷️ " + strings.Join(task.Labels, ",")
 			}
 
-			projectName := getProjectName(data.Projects, task.ProjectID)
+			projectName := idx.ProjectName(task.ProjectID)
 
 			dateInfo := dueString
 			if mode == "deadline" {
@@ -389,12 +321,33 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 			if mode != "deadline" && deadlineString != "" {
 				subtitleDeadline = " " + deadlineString
 			}
-			subtitle := fmt.Sprintf("%d/%d.%s%s%s", countR, matchCount, goalsString, labelsString, subtitleDeadline)
+			subtitleDesc := ""
+			if task.Description != "" {
+				subtitleDesc = " 📝 " + descriptionSnippet(task.Description)
+			}
+			subtitleParent := ""
+			if parent := idx.TaskContent(task.ParentID); parent != "" {
+				subtitleParent = " ↳ " + parent
+			}
+			if n := idx.Subtasks(task.ID); n > 0 {
+				subtitleParent += fmt.Sprintf(" 🌳 %d %s", n, pluralize(n, "subtask", "subtasks"))
+			}
+			subtitleComments := ""
+			if n := idx.Comments(task.ID); n > 0 {
+				subtitleComments = fmt.Sprintf(" 💬 %d", n)
+			}
+			subtitle := fmt.Sprintf("%d/%d.%s%s%s%s%s%s%s", countR, matchCount, goalsString, subtitleParent, labelsString, subtitleRecurrence, subtitleDeadline, subtitleComments, subtitleDesc)
+
+			// ⌘C / ⌘L show the full description
+			text := task.Content
+			if task.Description != "" {
+				text += "\n\n" + task.Description
+			}
 
 			// Build reconstructed input string for edit mode
-			editArg := reconstructEditInput(task, data.Projects)
+			editArg := reconstructEditInput(task, idx, now.Location())
 
-			output.Items = append(output.Items, alfred.OutputItem{
+			item := alfred.OutputItem{
 				Title:    title,
 				Subtitle: subtitle,
 				Arg:      "",
@@ -418,12 +371,39 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 					"cmd+ctrl+alt": {
 						Subtitle: "Delete this task 🗑️",
 					},
+					"cmd+ctrl": {
+						Subtitle: fmt.Sprintf("Comments (%d): read or add 💬", idx.Comments(task.ID)),
+						Variables: map[string]any{
+							"myTaskID":      task.ID,
+							"myTaskContent": task.Content,
+						},
+					},
+					"cmd+shift": {
+						Subtitle: fmt.Sprintf("Bulk actions on all %d matching tasks ⚡", matchCount),
+						Variables: map[string]any{
+							"myArg":  input,
+							"myMode": mode,
+						},
+					},
 				},
 				Icon: &alfred.Icon{Path: icon},
-			})
+				Text: &alfred.Text{Copy: text, LargeType: text},
+			}
+			if n := idx.Subtasks(task.ID); n > 0 {
+				// Drill into the subtasks, whatever their due dates
+				item.Mods["cmd+alt"] = alfred.ModsItem{
+					Subtitle: fmt.Sprintf("Show %d %s 🌳", n, pluralize(n, "subtask", "subtasks")),
+					Variables: map[string]any{
+						"myIter": true,
+						"myArg":  "parent:" + task.ID + " ",
+						"myMode": "all",
+					},
+				}
+			}
+			output.Items = append(output.Items, item)
 			countR++
 		}
-	} else if len(searchStrings) > 0 || len(filterLabels) > 0 {
+	} else if q.filtered() {
 		output.Items = append(output.Items, alfred.OutputItem{
 			Title:    "no tasks matching your query 🙁",
 			Subtitle: "",
@@ -449,6 +429,12 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 			emptyTitle = "no tasks with a deadline set"
 		case "all":
 			emptyTitle = "no tasks found"
+		case "now":
+			emptyTitle = fmt.Sprintf("nothing due in the next %d %s! 🙌", span, pluralize(span, "hour", "hours"))
+		case "upcoming":
+			emptyTitle = fmt.Sprintf("nothing due in the next %d %s! 🙌", span, pluralize(span, "day", "days"))
+		case "filter":
+			emptyTitle = "no tasks match this filter"
 		}
 		output.Items = append(output.Items, alfred.OutputItem{
 			Title:    emptyTitle,
@@ -463,13 +449,75 @@ func (s *TaskService) QueryTasks(mode, input string) (*alfred.Output, error) {
 	return output, nil
 }
 
+// refreshRerun is how often, in seconds, Alfred reruns a query while the
+// cache is refreshed in the background
+const refreshRerun = 1
+
+// syncStatus tells how long ago the cached data was fetched, e.g.
+// "🔄 synced 5 min ago", or that a refresh is under way
+func syncStatus(fetchedAt, now time.Time, refreshing bool) string {
+	if fetchedAt.IsZero() {
+		return ""
+	}
+	ago := "just now"
+	switch d := now.Sub(fetchedAt); {
+	case d >= 48*time.Hour:
+		ago = fmt.Sprintf("%d days ago", int(d.Hours()/24))
+	case d >= time.Hour:
+		ago = fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d >= time.Minute:
+		ago = fmt.Sprintf("%d min ago", int(d.Minutes()))
+	}
+	if refreshing {
+		return "🔄 syncing… (last " + ago + ")"
+	}
+	return "🔄 synced " + ago
+}
+
+// daySeparator heads a day of the upcoming agenda, e.g. "Tue Oct 20 — 4 tasks".
+// Selecting it just reruns the query.
+func daySeparator(day string, count int, today, input, mode string) alfred.OutputItem {
+	title := day
+	if d, err := time.Parse("2006-01-02", day); err == nil {
+		title = d.Format("Mon Jan 2")
+	}
+	subtitle := ""
+	switch n := todoist.DaysBetween(today, day); n {
+	case 0:
+		subtitle = "today"
+	case 1:
+		subtitle = "tomorrow"
+	default:
+		subtitle = fmt.Sprintf("in %d days", n)
+	}
+
+	return alfred.OutputItem{
+		Title:    fmt.Sprintf("%s — %d %s", title, count, pluralize(count, "task", "tasks")),
+		Subtitle: subtitle,
+		Arg:      "",
+		Variables: map[string]any{
+			"myIter": true,
+			"myArg":  input,
+			"myMode": mode,
+		},
+		Mods: map[string]alfred.ModsItem{
+			"shift": {Arg: "", Subtitle: "nothing to see here"},
+			"cmd":   {Arg: "", Subtitle: "nothing to see here"},
+			"ctrl":  {Arg: "", Subtitle: "nothing to see here"},
+			"alt":   {Arg: "", Subtitle: "nothing to see here"},
+		},
+		Icon: &alfred.Icon{Path: "icons/today.png"},
+	}
+}
+
 // ParseNewTask handles parse command
 func (s *TaskService) ParseNewTask(input string) (*alfred.Output, error) {
-	if err := s.cache.EnsureFresh(); err != nil {
+	if err := s.ensureFresh(""); err != nil {
 		return nil, err
 	}
 
 	data := s.cache.Data()
+	idx := data.Index()
 
 	// Load counts
 	labelCounts, err := s.cache.LoadLabelCounts()
@@ -500,6 +548,10 @@ func (s *TaskService) ParseNewTask(input string) (*alfred.Output, error) {
 		ProjectCounts: projectCounts,
 		PartialMatch:  s.cfg.PartialMatch,
 		Lang:          s.cfg.DueLang,
+		Tasks:         make([]parser.TaskRef, 0, len(data.Tasks)),
+	}
+	for _, t := range data.Tasks {
+		ctx.Tasks = append(ctx.Tasks, parser.TaskRef{ID: t.ID, Content: t.Content})
 	}
 
 	parsed, autocomplete, needsExit := parser.ParseNewTaskInput(input, ctx)
@@ -522,20 +574,25 @@ func (s *TaskService) ParseNewTask(input string) (*alfred.Output, error) {
 		return output, nil
 	}
 
-	// Resolve project ID
-	if parsed.ProjectName != "" {
+	// Resolve project ID. Subtasks always live in their parent's project.
+	if parent, ok := s.cache.Task(parsed.ParentID); ok {
+		parsed.ProjectID = parent.ProjectID
+		parsed.SectionID = parent.SectionID
+		parsed.ProjectName = "#" + idx.ProjectName(parent.ProjectID)
+		parsed.SectionName = idx.SectionName(parent.SectionID)
+	} else if parsed.ProjectName != "" {
 		projName := parsed.ProjectName
 		if strings.Contains(projName, "/") {
 			parts := strings.SplitN(projName, "/", 2)
-			parsed.ProjectID = getProjectID(data.Projects, parts[0][1:])
-			parsed.SectionID = getSectionID(data.Projects, data.Sections, projName)
+			parsed.ProjectID = idx.ProjectID(parts[0][1:])
+			parsed.SectionID = idx.SectionID(projName)
 			parsed.SectionName = parts[1]
 		} else {
-			parsed.ProjectID = getProjectID(data.Projects, projName[1:])
+			parsed.ProjectID = idx.ProjectID(projName[1:])
 		}
 	} else {
 		parsed.ProjectName = "#Inbox"
-		parsed.ProjectID = getProjectID(data.Projects, "Inbox")
+		parsed.ProjectID = idx.ProjectID("Inbox")
 	}
 
 	// Build preview
@@ -570,10 +627,23 @@ func (s *TaskService) ParseNewTask(input string) (*alfred.Output, error) {
 		deadlineStringF = "🎯 deadline:" + parsed.Deadline
 	}
 
+	var descStringF string
+	if parsed.Description != "" {
+		descStringF = "📝 " + descriptionSnippet(parsed.Description)
+	}
+	// Lets an edit tell an empty // (clear the description) from none
+	descGiven := ""
+	if parsed.DescGiven {
+		descGiven = "1"
+	}
+
 	projStringF := "📋" + parsed.ProjectName
+	if parsed.ParentName != "" {
+		projStringF = "↳ " + parsed.ParentName[1:] + " " + projStringF
+	}
 
-	subtitle := fmt.Sprintf("%s %s %s %s %s %s ⇧↩️ to create",
-		projStringF, sectStringF, tagStringF, prioStringF, dueStringF, deadlineStringF)
+	subtitle := fmt.Sprintf("%s %s %s %s %s %s %s ⇧↩️ to create",
+		projStringF, sectStringF, tagStringF, prioStringF, dueStringF, deadlineStringF, descStringF)
 
 	output.Items = append(output.Items, alfred.OutputItem{
 		Title:    parsed.Content,
@@ -584,12 +654,15 @@ func (s *TaskService) ParseNewTask(input string) (*alfred.Output, error) {
 			"myTagString":   tagString,
 			"myProjectID":   parsed.ProjectID,
 			"mySectionID":   parsed.SectionID,
+			"myParentID":    parsed.ParentID,
 			"myDueDate":     parsed.DueDate,
 			"myDueString":   parsed.DueString,
 			"myDueLang":     parsed.DueLang,
 			"myDeadline":    parsed.Deadline,
 			"myDeadlineRaw": parsed.DeadlineRaw,
 			"myPriority":    parsed.Priority,
+			"myDescription": parsed.Description,
+			"myDescGiven":   descGiven,
 		},
 		Icon: &alfred.Icon{Path: "icons/newTask.png"},
 	})
@@ -597,32 +670,45 @@ func (s *TaskService) ParseNewTask(input string) (*alfred.Output, error) {
 	return output, nil
 }
 
-// CompleteTask completes a task and refreshes cache
-func (s *TaskService) CompleteTask(taskID string) error {
-	if err := s.client.CompleteTask(taskID); err != nil {
-		return err
+// CompleteTask completes a task and refreshes cache. For a recurring task,
+// which stays open with its due date moved on, it returns the task's next
+// occurrence as now cached; otherwise nil.
+func (s *TaskService) CompleteTask(taskID string) (*todoist.Task, error) {
+	prior := s.priorTasks(taskID)
+	err := s.submit([]todoist.Command{todoist.ItemClose(taskID)}, func() error {
+		return s.client.CompleteTask(taskID)
+	})
+	if err := s.journal("complete", prior, err); err != nil {
+		return nil, err
 	}
-	// Refresh cache in background (best effort)
-	if err := s.cache.Refresh(); err != nil {
-		utils.Log("warning: cache refresh failed: %v", err)
+
+	if len(prior) == 0 || !prior[0].Recurring() {
+		return nil, nil
 	}
-	return nil
+	next, ok := s.cache.Task(taskID)
+	if !ok || next.Due == nil || (prior[0].Due != nil && next.Due.Date == prior[0].Due.Date) {
+		return nil, nil
+	}
+	return &next, nil
 }
 
 // DeleteTask deletes a task and refreshes cache
 func (s *TaskService) DeleteTask(taskID string) error {
-	if err := s.client.DeleteTask(taskID); err != nil {
-		return err
-	}
-	// Refresh cache in background (best effort)
-	if err := s.cache.Refresh(); err != nil {
-		utils.Log("warning: cache refresh failed: %v", err)
-	}
-	return nil
+	entry := cache.UndoEntry{Action: "delete", Tasks: s.priorTasks(taskID)}
+	if data := s.cache.Data(); data != nil {
+		// Todoist deletes them too, and undo can only recreate the task
+		entry.Subtasks = data.Index().Subtasks(taskID)
+		entry.Comments = data.Index().Comments(taskID)
+	}
+	err := s.submit([]todoist.Command{todoist.ItemDelete(taskID)}, func() error {
+		return s.client.DeleteTask(taskID)
+	})
+	return s.journalEntry(entry, err)
 }
 
-// CreateTask creates a new task via the API
-func (s *TaskService) CreateTask(content, labelsStr, projectID, sectionID, dueDate, dueString, dueLang string, priority int, deadline, deadlineLang string) error {
+// CreateTask creates a new task via the API and adds it to the cache. The
+// created task is nil if it was queued (ErrQueued) or replayed with the queue.
+func (s *TaskService) CreateTask(content, labelsStr, projectID, sectionID, parentID, dueDate, dueString, dueLang string, priority int, deadline, deadlineLang, description string) (*todoist.Task, error) {
 	var labels []string
 	if labelsStr != "" {
 		labels = strings.Split(labelsStr, ",,..,,")
@@ -641,22 +727,106 @@ func (s *TaskService) CreateTask(content, labelsStr, projectID, sectionID, dueDa
 		dueLang = s.cfg.DueLang
 	}
 
-	// Build description from TASK_STAMP template
-	description := ""
+	// Append the TASK_STAMP template to the description
 	if s.cfg.TaskStamp != "" {
-		description = strings.ReplaceAll(s.cfg.TaskStamp, "{timestamp}",
+		stamp := strings.ReplaceAll(s.cfg.TaskStamp, "{timestamp}",
 			time.Now().Format("Monday, January 2, 2006, 3:04:05 pm"))
+		if description != "" {
+			description += "\n\n"
+		}
+		description += stamp
+	}
+
+	// Sync API equivalent of the REST payload, used if the task has to be queued
+	args := map[string]any{
+		"content":  content,
+		"priority": priority,
+	}
+	if description != "" {
+		args["description"] = description
+	}
+	if len(labels) > 0 {
+		args["labels"] = labels
+	}
+	if projectID != "" {
+		args["project_id"] = projectID
+	}
+	if sectionID != "" {
+		args["section_id"] = sectionID
+	}
+	if parentID != "" {
+		args["parent_id"] = parentID
+	}
+	if dueString != "" {
+		args["due"] = map[string]any{"string": dueString, "lang": dueLang}
+	} else if dueDate != "" {
+		args["due"] = map[string]string{"date": dueDate}
 	}
+	if dl != nil {
+		args["deadline"] = map[string]string{"date": dl.Date, "lang": dl.Lang}
+	}
+	cmd := todoist.ItemAdd(todoist.NewUUID(), args)
 
-	if err := s.client.CreateTask(content, labels, projectID, sectionID, dueDate, dueString, dueLang, priority, dl, description); err != nil {
+	var created *todoist.Task
+	err := s.deliver([]todoist.Command{cmd}, func() error {
+		var err error
+		created, err = s.client.CreateTask(content, labels, projectID, sectionID, parentID, dueDate, dueString, dueLang, priority, dl, description)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created == nil {
+		// Replayed from the queue; the cache was resynced by the flush
+		return nil, nil
 	}
 
-	// Refresh cache
-	if err := s.cache.Refresh(); err != nil {
-		utils.Log("warning: cache refresh failed: %v", err)
+	if err := s.cache.PutTask(*created); err != nil {
+		utils.Log("warning: could not add created task to cache: %v", err)
+	}
+	return created, nil
+}
+
+// ProjectName returns the cached name of a project, or "" if unknown
+func (s *TaskService) ProjectName(id string) string {
+	data := s.cache.Data()
+	if data == nil {
+		if err := s.cache.Load(); err != nil {
+			return ""
+		}
+		data = s.cache.Data()
+	}
+	return data.Index().ProjectName(id)
+}
+
+// CreatedOutput tells the workflow what Todoist made of a new task: a
+// notification text to check it by, and the task's ID and links to open it
+func (s *TaskService) CreatedOutput(task *todoist.Task) *alfred.WorkflowOutput {
+	due := ""
+	if task.Due != nil {
+		due = task.Due.Date
+	}
+	project := s.ProjectName(task.ProjectID)
+	appURL := fmt.Sprintf("todoist://task?id=%s", task.ID)
+
+	details := task.Content
+	if due != "" {
+		details += " · due " + due
+	}
+	if project != "" {
+		details += " · #" + project
+	}
+	return &alfred.WorkflowOutput{
+		Arg: "🎯 task created!\n" + details,
+		Variables: map[string]any{
+			"myTaskID":      task.ID,
+			"myTaskContent": task.Content,
+			"myURL":         fmt.Sprintf("https://app.todoist.com/app/task/%s", task.ID),
+			"myAppURL":      appURL,
+			"myDue":         due,
+			"myProject":     project,
+		},
 	}
-	return nil
 }
 
 // CreateLabel creates a label and updates the counts file
@@ -686,18 +856,18 @@ func (s *TaskService) RescheduleTask(taskID, dateInput string) error {
 	newDate := parser.ResolveRescheduleDate(dateInput)
 	utils.Log("rescheduling task %s to %s", taskID, newDate)
 
-	updates := map[string]any{
-		"due": map[string]string{"date": newDate},
-	}
-
-	if err := s.client.UpdateTask(taskID, updates); err != nil {
-		return err
-	}
-
-	if err := s.cache.Refresh(); err != nil {
-		utils.Log("warning: cache refresh failed: %v", err)
+	prior := s.priorTasks(taskID)
+	task := todoist.Task{ID: taskID}
+	if len(prior) > 0 {
+		task = prior[0]
 	}
-	return nil
+	cmds := []todoist.Command{todoist.ItemUpdate(taskID, map[string]any{
+		"due": task.Reschedule(newDate),
+	})}
+	err := s.submit(cmds, func() error {
+		return s.sendCommands(cmds)
+	})
+	return s.journal("reschedule", prior, err)
 }
 
 // BuildRescheduleMenu builds the reschedule date menu
@@ -715,15 +885,32 @@ func (s *TaskService) BuildRescheduleMenu(customDays, taskContent string) *alfre
 	return output
 }
 
+// BackgroundRefresh replays queued mutations and refreshes the cache, as the
+// detached process started by ensureFresh
+func (s *TaskService) BackgroundRefresh() error {
+	defer s.cache.EndBackgroundRefresh()
+	if _, err := s.FlushQueue(); err != nil {
+		utils.Log("warning: could not replay queued changes: %v", err)
+	}
+	return s.cache.Refresh()
+}
+
 // ForceRebuild forces a cache refresh
 func (s *TaskService) ForceRebuild() (*alfred.Output, error) {
+	subtitle := "ready to use AlfreDo now ✅"
+	if _, err := s.FlushQueue(); err != nil {
+		utils.Log("warning: could not replay queued changes: %v", err)
+		if errors.Is(err, ErrRejected) {
+			subtitle = "⚠️ Todoist rejected some changes made offline, check debugger"
+		}
+	}
 	if err := s.cache.Refresh(); err != nil {
 		return nil, err
 	}
 	return &alfred.Output{
 		Items: []alfred.OutputItem{{
 			Title:    "Done!",
-			Subtitle: "ready to use AlfreDo now ✅",
+			Subtitle: subtitle,
 			Arg:      "",
 			Icon:     &alfred.Icon{Path: "icons/done.png"},
 		}},
@@ -732,15 +919,17 @@ func (s *TaskService) ForceRebuild() (*alfred.Output, error) {
 
 // GetStats returns completion statistics from the cache
 func (s *TaskService) GetStats() (*todoist.StatsResponse, error) {
-	if err := s.cache.EnsureFresh(); err != nil {
+	if err := s.ensureFresh(""); err != nil {
 		return nil, err
 	}
 	data := s.cache.Data()
 	return data.Stats, nil
 }
 
-// EditTask updates an existing task via the API
-func (s *TaskService) EditTask(taskID, content, labelsStr, projectID, sectionID, dueDate, dueString, dueLang string, priority int, deadline, deadlineLang string) error {
+// EditTask updates an existing task via the API. A nil description (no //
+// in the input) leaves the task's description unchanged; an empty one
+// clears it.
+func (s *TaskService) EditTask(taskID, content, labelsStr, projectID, sectionID, parentID, dueDate, dueString, dueLang string, priority int, deadline, deadlineLang string, description *string) error {
 	var labels []string
 	if labelsStr != "" {
 		labels = strings.Split(labelsStr, ",,..,,")
@@ -751,15 +940,18 @@ func (s *TaskService) EditTask(taskID, content, labelsStr, projectID, sectionID,
 		"labels":   labels,
 		"priority": priority,
 	}
-
-	if projectID != "" {
-		updates["project_id"] = projectID
-	}
-	if sectionID != "" {
-		updates["section_id"] = sectionID
+	if description != nil {
+		updates["description"] = *description
 	}
 
-	if dueString != "" {
+	current, known := s.cache.Task(taskID)
+	loc := s.location()
+
+	// An unchanged due date is left alone, keeping its recurrence rule and
+	// timezone. A recurring task keeps its rule unless a new due string
+	// replaces it; a new date only moves the current occurrence.
+	switch {
+	case dueString != "":
 		if dueLang == "" {
 			dueLang = s.cfg.DueLang
 		}
@@ -767,9 +959,11 @@ func (s *TaskService) EditTask(taskID, content, labelsStr, projectID, sectionID,
 			"string": dueString,
 			"lang":   dueLang,
 		}
-	} else if dueDate != "" {
-		updates["due"] = map[string]string{"date": dueDate}
-	} else {
+	case known && current.Due != nil && dueDate == editDueDate(current.Due, loc):
+		// unchanged
+	case dueDate != "":
+		updates["due"] = editedDue(current, dueDate, loc)
+	default:
 		updates["due"] = nil
 	}
 
@@ -786,21 +980,222 @@ func (s *TaskService) EditTask(taskID, content, labelsStr, projectID, sectionID,
 		updates["deadline"] = nil
 	}
 
-	if err := s.client.UpdateTask(taskID, updates); err != nil {
-		return err
+	// item_update can't change project, section or parent; move in the same
+	// request. The edit input only names the project, so a task keeps its
+	// section unless it is moved elsewhere.
+	cmds := []todoist.Command{todoist.ItemUpdate(taskID, updates)}
+	prior := s.priorTasks(taskID)
+	_, parentKnown := s.cache.Task(current.ParentID)
+	switch {
+	case parentID != "":
+		if !known || parentID != current.ParentID {
+			cmds = append(cmds, todoist.ItemMoveUnder(taskID, parentID))
+		}
+	case known && current.ParentID != "" && parentKnown:
+		// The ^parent pre-filled in the input (see reconstructEditInput) was
+		// removed: move the subtask to the top level
+		switch {
+		case sectionID != "":
+			cmds = append(cmds, todoist.ItemMove(taskID, "", sectionID))
+		case projectID != "" && projectID != current.ProjectID:
+			cmds = append(cmds, todoist.ItemMove(taskID, projectID, ""))
+		default:
+			cmds = append(cmds, todoist.ItemMove(taskID, current.ProjectID, current.SectionID))
+		}
+	case sectionID != "" && (!known || sectionID != current.SectionID):
+		cmds = append(cmds, todoist.ItemMove(taskID, "", sectionID))
+	case sectionID == "" && projectID != "" && (!known || projectID != current.ProjectID):
+		cmds = append(cmds, todoist.ItemMove(taskID, projectID, ""))
 	}
+	err := s.submit(cmds, func() error {
+		return s.sendCommands(cmds)
+	})
+	return s.journal("edit", prior, err)
+}
 
-	if err := s.cache.Refresh(); err != nil {
+// FlushQueue replays queued mutations, in order and in as few Sync requests
+// as possible, then resyncs the cache. Commands queued meanwhile wait for
+// the replay and are kept. Returns the number of commands Todoist applied;
+// if it rejected any, the error wraps ErrRejected.
+func (s *TaskService) FlushQueue() (int, error) {
+	changedAt := time.Now()
+	pending, resp, err := s.cache.ReplayQueue(func(cmds []todoist.Command) (*todoist.CommandsResponse, error) {
+		utils.Log("replaying %d queued commands", len(cmds))
+		return s.client.ExecuteCommands(cmds)
+	})
+	if resp == nil || len(resp.SyncStatus) == 0 {
+		return 0, err
+	}
+	if err != nil {
+		utils.Log("warning: replay stopped part way, the rest stays queued: %v", err)
+	}
+
+	// Commands rejected by the server would be rejected again, so they were
+	// dropped too
+	applied := 0
+	var rejected []error
+	for _, cmd := range pending {
+		if _, sent := resp.SyncStatus[cmd.UUID]; !sent {
+			continue
+		}
+		if cErr := resp.Err(cmd.UUID); cErr != nil {
+			utils.Log("warning: queued %s was rejected: %v", cmd.Type, cErr)
+			rejected = append(rejected, fmt.Errorf("%s: %w", cmd.Type, cErr))
+			continue
+		}
+		applied++
+	}
+
+	// Local changes were only optimistic; replace them with the server state
+	s.cache.ResetSyncToken()
+	s.refreshAfterChange(changedAt)
+	if len(rejected) > 0 {
+		err = errors.Join(err, fmt.Errorf("%w: %w", ErrRejected, errors.Join(rejected...)))
+	}
+	return applied, err
+}
+
+// --- helpers ---
+
+// ensureFresh loads the cache, refreshing it if it is older than the max age
+// for mode. A stale cache is still used as is while a background process
+// refreshes it; only without one to show, or when the mode should always be
+// fresh (a max age of 0), do we wait for the download, replaying queued
+// mutations first so the server state includes them.
+func (s *TaskService) ensureFresh(mode string) error {
+	maxAge := s.cfg.MaxAge(mode)
+	if s.cache.OlderThan(maxAge) {
+		if maxAge > 0 && s.cfg.DataFolder != "" && s.cache.Load() == nil && s.cache.Data() != nil {
+			err := s.startRefresh()
+			if err == nil {
+				return nil
+			}
+			utils.Log("warning: could not refresh in the background: %v", err)
+		}
+		if _, err := s.FlushQueue(); err != nil {
+			utils.Log("warning: could not replay queued changes: %v", err)
+		}
+	}
+	return s.cache.EnsureMaxAge(maxAge)
+}
+
+// location returns the timezone due dates are resolved in: TIMEZONE if set,
+// else the Todoist account's timezone, else the system's
+func (s *TaskService) location() *time.Location {
+	name := s.cfg.Timezone
+	if name == "" {
+		if data := s.cache.Data(); data != nil && data.User != nil {
+			name = data.User.TZInfo.Timezone
+		}
+	}
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		utils.Log("warning: unknown timezone %q, using local time: %v", name, err)
+		return time.Local
+	}
+	return loc
+}
+
+// submit performs a mutation through deliver and refreshes the cache once
+// send went through
+func (s *TaskService) submit(cmds []todoist.Command, send func() error) error {
+	changedAt := time.Now()
+	sent := false
+	err := s.deliver(cmds, func() error {
+		if err := send(); err != nil {
+			return err
+		}
+		sent = true
+		return nil
+	})
+	if sent {
+		s.refreshAfterChange(changedAt)
+	}
+	return err
+}
+
+// refreshAfterChange resyncs the cache after a change made at changedAt. If
+// that fails, or another process was already refreshing and its data
+// predates the change, the cache is invalidated so the next query refreshes
+// it instead of showing the change undone.
+func (s *TaskService) refreshAfterChange(changedAt time.Time) {
+	err := s.cache.Refresh()
+	if err != nil {
 		utils.Log("warning: cache refresh failed: %v", err)
 	}
+	if data := s.cache.Data(); err != nil || data == nil || data.FetchedAt.Before(changedAt) {
+		if err := s.cache.Invalidate(); err != nil {
+			utils.Log("warning: could not invalidate the cache: %v", err)
+		}
+	}
+}
+
+// deliver performs a mutation through send. If Todoist can't be reached, cmds
+// (the Sync API equivalent of send) are queued for replay and ErrQueued is
+// returned. While older commands are still queued, cmds are queued behind
+// them and the queue is flushed so that mutations reach the server in order.
+func (s *TaskService) deliver(cmds []todoist.Command, send func() error) error {
+	pending, err := s.cache.LoadQueue()
+	if err != nil {
+		utils.Log("warning: could not read queue: %v", err)
+	}
+
+	if len(pending) > 0 {
+		if err := s.enqueue(cmds); err != nil {
+			return err
+		}
+		if _, err := s.FlushQueue(); err != nil {
+			if todoist.IsNetworkError(err) {
+				return ErrQueued
+			}
+			return err
+		}
+		return nil
+	}
+
+	if err := send(); err != nil {
+		if !todoist.IsNetworkError(err) {
+			return err
+		}
+		utils.Log("todoist unreachable, queueing %d commands: %v", len(cmds), err)
+		if qErr := s.enqueue(cmds); qErr != nil {
+			return fmt.Errorf("%w (and queueing failed: %v)", err, qErr)
+		}
+		return ErrQueued
+	}
 	return nil
 }
 
-// --- helpers ---
+func (s *TaskService) enqueue(cmds []todoist.Command) error {
+	for _, cmd := range cmds {
+		if err := s.cache.Enqueue(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendCommands executes Sync API commands in one request, failing on the
+// first command Todoist rejected
+func (s *TaskService) sendCommands(cmds []todoist.Command) error {
+	resp, err := s.client.ExecuteCommands(cmds)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if err := resp.Err(cmd.UUID); err != nil {
+			return fmt.Errorf("%s failed: %w", cmd.Type, err)
+		}
+	}
+	return nil
+}
 
 // reconstructEditInput builds a string that mirrors what the user would type to create a task,
-// used for pre-populating the edit input field
-func reconstructEditInput(task todoist.Task, projects []todoist.Project) string {
+// used for pre-populating the edit input field. loc is the user's timezone.
+func reconstructEditInput(task todoist.Task, idx *cache.Index, loc *time.Location) string {
 	parts := []string{task.Content}
 
 	// Labels
@@ -813,7 +1208,7 @@ func reconstructEditInput(task todoist.Task, projects []todoist.Project) string
 	}
 
 	// Project (skip Inbox as it's the default)
-	projectName := getProjectName(projects, task.ProjectID)
+	projectName := idx.ProjectName(task.ProjectID)
 	if projectName != "" && projectName != "Inbox" {
 		if strings.Contains(projectName, " ") {
 			parts = append(parts, "#("+projectName+")")
@@ -822,6 +1217,15 @@ func reconstructEditInput(task todoist.Task, projects []todoist.Project) string
 		}
 	}
 
+	// Parent task
+	if parent := idx.TaskContent(task.ParentID); parent != "" {
+		if strings.Contains(parent, " ") {
+			parts = append(parts, "^("+parent+")")
+		} else {
+			parts = append(parts, "^"+parent)
+		}
+	}
+
 	// Priority (reverse-map: API 4→p1, 3→p2, 2→p3, 1→default/skip)
 	switch task.Priority {
 	case 4:
@@ -833,17 +1237,8 @@ func reconstructEditInput(task todoist.Task, projects []todoist.Project) string
 	}
 
 	// Due date
-	if task.Due != nil && task.Due.Date != "" {
-		if strings.Contains(task.Due.Date, "T") {
-			// Has time component: due:YYYY-MM-DDTHH:MM
-			datePart := task.Due.Date
-			if len(datePart) > 16 {
-				datePart = datePart[:16] // Trim seconds
-			}
-			parts = append(parts, "due:"+datePart)
-		} else {
-			parts = append(parts, "due:"+task.Due.Date)
-		}
+	if due := editDueDate(task.Due, loc); due != "" {
+		parts = append(parts, "due:"+due)
 	}
 
 	// Deadline
@@ -851,133 +1246,89 @@ func reconstructEditInput(task todoist.Task, projects []todoist.Project) string
 		parts = append(parts, "{"+task.Deadline.Date+"}")
 	}
 
-	return strings.Join(parts, " ")
-}
-
-func parseDueDate(dateStr string) time.Time {
-	if strings.Contains(dateStr, "T") {
-		if strings.Contains(dateStr, "Z") {
-			t, _ := time.Parse("2006-01-02T15:04:05Z", dateStr)
-			return t
-		}
-		t, _ := time.Parse("2006-01-02T15:04:05", dateStr)
-		if t.IsZero() {
-			t, _ = time.Parse("2006-01-02T15:04", dateStr)
-		}
-		return t
+	// Description, last since everything after // belongs to it. Multi-line
+	// descriptions can't be edited in Alfred's single-line input and are kept.
+	if task.Description != "" && !strings.Contains(task.Description, "\n") {
+		parts = append(parts, "// "+task.Description)
 	}
-	t, _ := time.Parse("2006-01-02", dateStr)
-	return t
-}
-
-func getProjectName(projects []todoist.Project, id string) string {
-	for _, p := range projects {
-		if p.ID == id {
-			return p.Name
-		}
-	}
-	return ""
-}
 
-func getProjectID(projects []todoist.Project, name string) string {
-	normalized := parser.NormalizeUnicode(name)
-	for _, p := range projects {
-		if parser.NormalizeUnicode(p.Name) == normalized {
-			return p.ID
-		}
-	}
-	return ""
+	return strings.Join(parts, " ")
 }
 
-func getSectionID(projects []todoist.Project, sections []todoist.Section, nameS string) string {
-	parts := strings.SplitN(nameS, "/", 2)
-	if len(parts) < 2 {
+// editDueDate writes a due date as the edit input takes it: YYYY-MM-DD, or
+// YYYY-MM-DDTHH:MM for a due time. A time pinned to a timezone is written at
+// its wall-clock time there.
+func editDueDate(due *todoist.Due, loc *time.Location) string {
+	if due == nil || due.Date == "" {
 		return ""
 	}
-	projName := parts[0]
-	if strings.HasPrefix(projName, "#") {
-		projName = projName[1:]
-	}
-	sectName := parts[1]
-	projID := getProjectID(projects, projName)
-
-	for _, s := range sections {
-		if s.Name == sectName && s.ProjectID == projID {
-			return s.ID
+	if zone := due.Zone(loc); zone != nil {
+		if t, _, ok := due.In(zone); ok {
+			return t.Format("2006-01-02T15:04")
 		}
 	}
-	return ""
-}
-
-func filterTasks(tasks []todoist.Task, labels, projects, sections, search []string) []todoist.Task {
-	if len(labels) == 0 && len(projects) == 0 && len(sections) == 0 && len(search) == 0 {
-		return tasks
+	date := due.Date
+	if len(date) > 16 && strings.Contains(date, "T") {
+		date = date[:16] // Trim seconds
 	}
-
-	var result []todoist.Task
-	for _, t := range tasks {
-		if !matchLabels(t, labels) {
-			continue
-		}
-		if !matchProjects(t, projects) {
-			continue
-		}
-		if len(sections) > 0 && !matchSections(t, sections) {
-			continue
-		}
-		if !matchSearch(t, search) {
-			continue
-		}
-		result = append(result, t)
-	}
-	return result
+	return date
 }
 
-func matchLabels(t todoist.Task, labels []string) bool {
-	for _, l := range labels {
-		found := false
-		for _, tl := range t.Labels {
-			if tl == l {
-				found = true
-				break
+// editedDue returns the due arguments for a task's new date from the edit
+// input. A task pinned to a timezone stays pinned: the new time is taken in
+// that timezone and sent as the UTC instant, as Todoist stores it.
+func editedDue(task todoist.Task, date string, loc *time.Location) map[string]any {
+	due := task.Reschedule(date)
+	zone := task.Due.Zone(loc)
+	if zone == nil || !strings.Contains(date, "T") {
+		return due
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, date, zone); err == nil {
+			due["date"] = t.UTC().Format(time.RFC3339)
+			if task.Due.Timezone != "" {
+				due["timezone"] = task.Due.Timezone
 			}
-		}
-		if !found {
-			return false
+			break
 		}
 	}
-	return true
+	return due
 }
 
-func matchProjects(t todoist.Task, projects []string) bool {
-	for _, p := range projects {
-		if !strings.Contains(t.ProjectID, p) {
-			return false
+// matchSearch requires every search word in the content or description,
+// ignoring case and accents.
+// Words prefixed with desc: only match the description; a bare desc: matches
+// any task that has one.
+func matchSearch(t todoist.Task, search []string) bool {
+	contentLower := utils.Fold(t.Content)
+	descLower := utils.Fold(t.Description)
+	for _, s := range search {
+		term := utils.Fold(s)
+		if rest, ok := strings.CutPrefix(term, "desc:"); ok {
+			if descLower == "" || !strings.Contains(descLower, rest) {
+				return false
+			}
+			continue
 		}
-	}
-	return true
-}
-
-func matchSections(t todoist.Task, sections []string) bool {
-	if t.SectionID == "" {
-		return false
-	}
-	for _, s := range sections {
-		if !strings.Contains(t.SectionID, s) {
+		if !strings.Contains(contentLower, term) && !strings.Contains(descLower, term) {
 			return false
 		}
 	}
 	return true
 }
 
-func matchSearch(t todoist.Task, search []string) bool {
-	contentLower := strings.ToLower(t.Content)
-	for _, s := range search {
-		if !strings.Contains(contentLower, strings.ToLower(s)) {
-			return false
-		}
+// descriptionSnippet returns the first line of a description, shortened
+// to fit in a subtitle
+func descriptionSnippet(desc string) string {
+	line, _, more := strings.Cut(strings.TrimSpace(desc), "\n")
+	runes := []rune(line)
+	if len(runes) > 50 {
+		return string(runes[:50]) + "…"
 	}
-	return true
+	if more {
+		return line + " …"
+	}
+	return line
 }
 
 func unwrapParens(item, prefix string) string {
@@ -997,16 +1348,6 @@ func formatWithParens(item, prefix string) string {
 	return item
 }
 
-func containsStr(slice []string, s string) bool {
-	normalized := parser.NormalizeUnicode(s)
-	for _, v := range slice {
-		if parser.NormalizeUnicode(v) == normalized {
-			return true
-		}
-	}
-	return false
-}
-
 func removeElement(slice []string, elem string) []string {
 	result := make([]string, 0, len(slice))
 	removed := false
@@ -0,0 +1,1005 @@
+package service
+
+import (
+	"alfredo-go/pkg/alfred"
+	"alfredo-go/pkg/cache"
+	"alfredo-go/pkg/config"
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/todoist/todoisttest"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "test-token"
+
+// newTestService wires a TaskService to a fake Todoist server seeded with a
+// project, a section, a label, two tasks due today and an undated subtask of
+// the first, one comment, with a populated cache
+func newTestService(t *testing.T) (*TaskService, *todoisttest.Server) {
+	t.Helper()
+	srv := todoisttest.NewServer(testToken)
+	t.Cleanup(srv.Close)
+
+	today := time.Now().Format("2006-01-02")
+	srv.AddProject(todoist.Project{ID: "p1", Name: "Work"})
+	srv.AddProject(todoist.Project{ID: "p2", Name: "Home"})
+	srv.AddSection(todoist.Section{ID: "s1", Name: "Urgent", ProjectID: "p1"})
+	srv.AddLabel(todoist.Label{ID: "l1", Name: "waiting"})
+	srv.AddTask(todoist.Task{ID: "t1", Content: "write report", ProjectID: "p1", Priority: 1,
+		Labels: []string{"waiting"}, Due: &todoist.Due{Date: today}})
+	srv.AddTask(todoist.Task{ID: "t2", Content: "buy milk", ProjectID: "p2", Priority: 1,
+		Description: "oat, not cow", Due: &todoist.Due{Date: today}})
+	srv.AddTask(todoist.Task{ID: "t3", Content: "collect figures", ProjectID: "p1", Priority: 1,
+		ParentID: "t1"})
+	srv.AddNote(todoist.Note{ItemID: "t1", Content: "draft sent to Ann", PostedAt: "2026-10-01T09:00:00Z"})
+
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour, DueLang: "en"}
+	client := todoist.NewClient(testToken, srv.URL)
+	client.SetRetries(1, 0)
+	s := NewTaskService(client, cache.NewCache(client, cfg), cfg)
+	// Never spawn the test binary; stale caches are refreshed in place
+	s.startRefresh = func() error { return errors.New("no background refresh in tests") }
+
+	// Like a query in Alfred would, populate the cache before any mutation
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatalf("initial refresh: %v", err)
+	}
+	return s, srv
+}
+
+func TestQueryTasks_FiltersByLabel(t *testing.T) {
+	s, _ := newTestService(t)
+
+	out, err := s.QueryTasks("today", "@waiting ")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if len(out.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(out.Items))
+	}
+	item := out.Items[0]
+	if item.Variables["myTaskID"] != "t1" {
+		t.Errorf("myTaskID = %v, want t1", item.Variables["myTaskID"])
+	}
+	if !strings.Contains(item.Title, "#Work") {
+		t.Errorf("title %q should name the project", item.Title)
+	}
+}
+
+func TestQueryTasks_SearchesDescription(t *testing.T) {
+	s, _ := newTestService(t)
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"oat", []string{"t2"}},
+		{"desc:oat", []string{"t2"}},
+		{"desc:milk", nil},
+		{"desc:", []string{"t2"}},
+		{"report", []string{"t1"}},
+	}
+	for _, tt := range tests {
+		out, err := s.QueryTasks("today", tt.input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", tt.input, err)
+		}
+		var got []string
+		for _, item := range out.Items {
+			if id, ok := item.Variables["myTaskID"].(string); ok {
+				got = append(got, id)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("QueryTasks(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	out, _ := s.QueryTasks("today", "milk")
+	if item := out.Items[0]; !strings.Contains(item.Subtitle, "📝 oat, not cow") || item.Text == nil {
+		t.Errorf("item = %+v, want a description snippet and copy text", item)
+	}
+}
+
+func TestQueryTasks_Subtasks(t *testing.T) {
+	s, _ := newTestService(t)
+
+	ids := func(input string) string {
+		out, err := s.QueryTasks("all", input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", input, err)
+		}
+		var got []string
+		for _, item := range out.Items {
+			if id, ok := item.Variables["myTaskID"].(string); ok {
+				got = append(got, id)
+			}
+		}
+		return strings.Join(got, ",")
+	}
+	if got := ids("is:sub"); got != "t3" {
+		t.Errorf("is:sub = %s, want t3", got)
+	}
+	if got := ids("is:top"); got != "t1,t2" {
+		t.Errorf("is:top = %s, want t1,t2", got)
+	}
+	if got := ids("parent:t1"); got != "t3" {
+		t.Errorf("parent:t1 = %s, want t3", got)
+	}
+
+	out, _ := s.QueryTasks("all", "")
+	for _, item := range out.Items {
+		switch item.Variables["myTaskID"] {
+		case "t1":
+			drill, ok := item.Mods["cmd+alt"]
+			if !ok || drill.Variables["myArg"] != "parent:t1 " {
+				t.Errorf("parent item mods = %+v, want a drill-down into its subtasks", item.Mods)
+			}
+		case "t3":
+			if !strings.Contains(item.Subtitle, "↳ write report") {
+				t.Errorf("subtask subtitle = %q, want the parent's content", item.Subtitle)
+			}
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	s, srv := newTestService(t)
+
+	out, _ := s.QueryTasks("today", "report")
+	if !strings.Contains(out.Items[0].Subtitle, "💬 1") {
+		t.Errorf("subtitle = %q, want the comment count", out.Items[0].Subtitle)
+	}
+
+	out, err := s.Comments("t1", "")
+	if err != nil {
+		t.Fatalf("Comments: %v", err)
+	}
+	if len(out.Items) != 1 || out.Items[0].Title != "draft sent to Ann" {
+		t.Fatalf("comments = %+v, want the seeded comment", out.Items)
+	}
+
+	out, _ = s.Comments("t1", "approved")
+	if len(out.Items) != 1 || out.Items[0].Arg != "approved" {
+		t.Fatalf("comments = %+v, want only an add item", out.Items)
+	}
+
+	if err := s.AddComment("t1", "approved"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if notes := srv.Notes("t1"); len(notes) != 2 || notes[1].Content != "approved" {
+		t.Errorf("server notes = %+v, want the new comment", notes)
+	}
+	out, _ = s.Comments("t1", "")
+	if len(out.Items) != 2 || out.Items[0].Title != "approved" {
+		t.Errorf("comments = %+v, want the new comment first", out.Items)
+	}
+}
+
+func TestCompleteTask(t *testing.T) {
+	s, srv := newTestService(t)
+
+	next, err := s.CompleteTask("t1")
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if next != nil {
+		t.Errorf("CompleteTask returned next occurrence %+v for a one-off task", next)
+	}
+	if task, _ := srv.Task("t1"); !task.Checked {
+		t.Error("task was not completed on the server")
+	}
+	if _, ok := s.cache.Task("t1"); ok {
+		t.Error("completed task should be gone from the cache")
+	}
+}
+
+func TestRecurringTask(t *testing.T) {
+	s, srv := newTestService(t)
+	srv.AddTask(todoist.Task{ID: "r1", Content: "water plants", Priority: 1,
+		Due: &todoist.Due{Date: "2026-10-19", String: "every week", Lang: "en", IsRecurring: true}})
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := s.QueryTasks("all", "plants")
+	if item := out.Items[0]; !strings.Contains(item.Title, "🔁") || !strings.Contains(item.Subtitle, "🔁 every week") {
+		t.Errorf("item = %q / %q, want the recurrence marker and rule", item.Title, item.Subtitle)
+	}
+
+	if err := s.RescheduleTask("r1", "2026-10-21"); err != nil {
+		t.Fatalf("RescheduleTask: %v", err)
+	}
+	task, _ := srv.Task("r1")
+	if task.Due == nil || task.Due.Date != "2026-10-21" || !task.Due.IsRecurring || task.Due.String != "every week" {
+		t.Fatalf("due after reschedule = %+v, want the recurrence kept", task.Due)
+	}
+
+	// An edit that leaves the date alone must not send a plain date
+	if err := s.EditTask("r1", "water all plants", "", "", "", "", "2026-10-21", "", "", 1, "", "", nil); err != nil {
+		t.Fatalf("EditTask: %v", err)
+	}
+	if task, _ := srv.Task("r1"); task.Due == nil || !task.Due.IsRecurring {
+		t.Fatalf("due after edit = %+v, want the recurrence kept", task.Due)
+	}
+
+	next, err := s.CompleteTask("r1")
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if next == nil || next.Due.Date != "2026-10-28" {
+		t.Errorf("next occurrence = %+v, want due 2026-10-28", next)
+	}
+}
+
+func TestCreateTask(t *testing.T) {
+	s, srv := newTestService(t)
+	syncs := len(srv.SyncTokens())
+
+	s.cfg.TaskStamp = "via AlfreDo"
+	task, err := s.CreateTask("call mom", "waiting", "p2", "", "", "2026-10-20", "", "", 4, "", "", "ask about Sunday")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if task == nil || task.ID == "" {
+		t.Fatalf("CreateTask returned %+v, want the created task", task)
+	}
+	if task.Due == nil || task.Due.Date != "2026-10-20" {
+		t.Errorf("due = %+v, want 2026-10-20", task.Due)
+	}
+	if task.Description != "ask about Sunday\n\nvia AlfreDo" {
+		t.Errorf("description = %q, want the text followed by the stamp", task.Description)
+	}
+
+	remote, ok := srv.Task(task.ID)
+	if !ok || remote.ProjectID != "p2" || remote.Priority != 4 || len(remote.Labels) != 1 {
+		t.Errorf("server task = %+v", remote)
+	}
+	if cached, ok := s.cache.Task(task.ID); !ok || cached.Content != "call mom" {
+		t.Error("created task should be in the cache")
+	}
+	if n := len(srv.SyncTokens()); n != syncs {
+		t.Errorf("creating a task triggered %d syncs, want none", n-syncs)
+	}
+	if s.ProjectName(task.ProjectID) != "Home" {
+		t.Errorf("ProjectName = %q, want Home", s.ProjectName(task.ProjectID))
+	}
+
+	raw, err := s.CreatedOutput(task).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Alfredworkflow alfred.WorkflowOutput `json:"alfredworkflow"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("create output %s: %v", raw, err)
+	}
+	vars := out.Alfredworkflow.Variables
+	if vars["myTaskID"] != task.ID || vars["myAppURL"] != "todoist://task?id="+task.ID {
+		t.Errorf("create variables = %v, want the ID and link of %s", vars, task.ID)
+	}
+	if want := "🎯 task created!\ncall mom · due 2026-10-20 · #Home"; out.Alfredworkflow.Arg != want {
+		t.Errorf("create arg = %q, want %q", out.Alfredworkflow.Arg, want)
+	}
+}
+
+func TestCreateSubtask(t *testing.T) {
+	s, srv := newTestService(t)
+
+	out, err := s.ParseNewTask("check numbers ^write")
+	if err != nil {
+		t.Fatalf("ParseNewTask: %v", err)
+	}
+	if len(out.Items) != 1 || out.Items[0].Arg != "check numbers ^(write report) " {
+		t.Fatalf("autocomplete = %+v, want the parent task", out.Items)
+	}
+
+	out, _ = s.ParseNewTask(out.Items[0].Arg)
+	vars := out.Items[0].Variables
+	if vars["myParentID"] != "t1" || vars["myProjectID"] != "p1" {
+		t.Fatalf("variables = %v, want parent t1 in project p1", vars)
+	}
+
+	task, err := s.CreateTask("check numbers", "", "p1", "", "t1", "", "", "", 1, "", "", "")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if remote, _ := srv.Task(task.ID); remote.ParentID != "t1" {
+		t.Errorf("parent = %q, want t1", remote.ParentID)
+	}
+}
+
+func TestEditTask_MovesToSection(t *testing.T) {
+	s, srv := newTestService(t)
+
+	if err := s.EditTask("t2", "buy oat milk", "", "p1", "s1", "", "", "", "", 3, "", "", nil); err != nil {
+		t.Fatalf("EditTask: %v", err)
+	}
+	task, _ := srv.Task("t2")
+	if task.Content != "buy oat milk" || task.Priority != 3 {
+		t.Errorf("task = %+v, want updated content and priority", task)
+	}
+	if task.ProjectID != "p1" || task.SectionID != "s1" {
+		t.Errorf("task is in %s/%s, want p1/s1", task.ProjectID, task.SectionID)
+	}
+	if task.Due != nil {
+		t.Error("due date should have been cleared")
+	}
+}
+
+func TestEditTask_Description(t *testing.T) {
+	s, srv := newTestService(t)
+
+	// No // in the input keeps the description
+	if err := s.EditTask("t2", "buy milk", "", "p2", "", "", "", "", "", 1, "", "", nil); err != nil {
+		t.Fatalf("EditTask: %v", err)
+	}
+	if task, _ := srv.Task("t2"); task.Description != "oat, not cow" {
+		t.Errorf("description = %q, want it unchanged", task.Description)
+	}
+
+	// An empty // clears it
+	out, _ := s.ParseNewTask("buy milk //")
+	if got := out.Items[0].Variables["myDescGiven"]; got != "1" {
+		t.Fatalf("myDescGiven = %v, want 1 for an empty //", got)
+	}
+	empty := ""
+	if err := s.EditTask("t2", "buy milk", "", "p2", "", "", "", "", "", 1, "", "", &empty); err != nil {
+		t.Fatalf("EditTask: %v", err)
+	}
+	if task, _ := srv.Task("t2"); task.Description != "" {
+		t.Errorf("description = %q, want it cleared", task.Description)
+	}
+}
+
+func TestEditTask_RemovesParent(t *testing.T) {
+	s, srv := newTestService(t)
+
+	// The pre-filled input names the parent; without it the subtask moves up
+	task, _ := s.cache.Task("t3")
+	if input := reconstructEditInput(task, s.cache.Data().Index(), time.UTC); !strings.Contains(input, "^(write report)") {
+		t.Fatalf("edit input %q should name the parent", input)
+	}
+	if err := s.EditTask("t3", "collect figures", "", "p1", "", "", "", "", "", 1, "", "", nil); err != nil {
+		t.Fatalf("EditTask: %v", err)
+	}
+	task, _ = srv.Task("t3")
+	if task.ParentID != "" || task.ProjectID != "p1" {
+		t.Errorf("task = parent %q in %s, want top level in p1", task.ParentID, task.ProjectID)
+	}
+}
+
+func TestRescheduleTask(t *testing.T) {
+	s, srv := newTestService(t)
+
+	if err := s.RescheduleTask("t1", "2026-12-24"); err != nil {
+		t.Fatalf("RescheduleTask: %v", err)
+	}
+	if task, _ := srv.Task("t1"); task.Due == nil || task.Due.Date != "2026-12-24" {
+		t.Errorf("due = %+v, want 2026-12-24", task.Due)
+	}
+}
+
+func TestOfflineQueueAndFlush(t *testing.T) {
+	s, srv := newTestService(t)
+
+	// Point the service at a server that is no longer running
+	down := todoisttest.NewServer(testToken)
+	down.Close()
+	online := s.client
+	s.client = todoist.NewClient(testToken, down.URL)
+	s.client.SetRetries(0, 0)
+
+	if _, err := s.CompleteTask("t1"); !errors.Is(err, ErrQueued) {
+		t.Fatalf("CompleteTask err = %v, want ErrQueued", err)
+	}
+	if _, ok := s.cache.Task("t1"); ok {
+		t.Error("queued completion should already apply to the cache")
+	}
+	if task, _ := srv.Task("t1"); task.Checked {
+		t.Fatal("server should not have seen the completion yet")
+	}
+
+	s.client = online
+	n, err := s.FlushQueue()
+	if err != nil || n != 1 {
+		t.Fatalf("FlushQueue = %d, %v; want 1 replayed command", n, err)
+	}
+	if task, _ := srv.Task("t1"); !task.Checked {
+		t.Error("queued completion was not replayed")
+	}
+	if pending, _ := s.cache.LoadQueue(); len(pending) != 0 {
+		t.Errorf("queue still holds %d commands", len(pending))
+	}
+}
+
+func TestFlushQueue_ReportsRejected(t *testing.T) {
+	s, srv := newTestService(t)
+
+	if err := s.cache.SaveQueue([]todoist.Command{
+		todoist.ItemClose("t1"),
+		todoist.ItemClose("gone"),
+	}); err != nil {
+		t.Fatalf("SaveQueue: %v", err)
+	}
+	n, err := s.FlushQueue()
+	if !errors.Is(err, ErrRejected) || n != 1 {
+		t.Fatalf("FlushQueue = %d, %v; want 1 replayed and ErrRejected", n, err)
+	}
+	if task, _ := srv.Task("t1"); !task.Checked {
+		t.Error("the accepted completion was not replayed")
+	}
+	if pending, _ := s.cache.LoadQueue(); len(pending) != 0 {
+		t.Errorf("rejected commands should leave the queue, %d left", len(pending))
+	}
+}
+
+func TestEditTask_KeepsTimezone(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Rome"); err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	s, srv := newTestService(t)
+	s.cfg.Timezone = "America/New_York"
+	// 14:30 in Rome, where the task is pinned
+	srv.AddTask(todoist.Task{ID: "t4", Content: "call Rome", ProjectID: "p1",
+		Due: &todoist.Due{Date: "2026-10-20T12:30:00Z", Timezone: "Europe/Rome"}})
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	task, _ := s.cache.Task("t4")
+	if input := reconstructEditInput(task, s.cache.Data().Index(), s.location()); !strings.Contains(input, "due:2026-10-20T14:30") {
+		t.Fatalf("edit input %q should show the time in Rome", input)
+	}
+
+	// Saving the pre-filled date leaves the due date alone
+	if err := s.EditTask("t4", "call Rome office", "", "p1", "", "", "2026-10-20T14:30", "", "", 1, "", "", nil); err != nil {
+		t.Fatalf("EditTask: %v", err)
+	}
+	if task, _ := srv.Task("t4"); task.Due == nil || task.Due.Date != "2026-10-20T12:30:00Z" || task.Due.Timezone != "Europe/Rome" {
+		t.Errorf("unchanged due = %+v, want it untouched", task.Due)
+	}
+
+	// A new time is taken in Rome too
+	if err := s.EditTask("t4", "call Rome office", "", "p1", "", "", "2026-10-21T09:00", "", "", 1, "", "", nil); err != nil {
+		t.Fatalf("EditTask: %v", err)
+	}
+	if task, _ := srv.Task("t4"); task.Due == nil || task.Due.Date != "2026-10-21T07:00:00Z" || task.Due.Timezone != "Europe/Rome" {
+		t.Errorf("edited due = %+v, want 07:00 UTC pinned to Europe/Rome", task.Due)
+	}
+}
+
+func TestSelectTasks_Timezone(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	data := &cache.CachedData{Tasks: []todoist.Task{
+		{ID: "late", Due: &todoist.Due{Date: "2026-10-18T23:30:00"}},
+		{ID: "fixed", Due: &todoist.Due{Date: "2026-10-18T22:30:00Z", Timezone: "UTC"}},
+		{ID: "morning", Due: &todoist.Due{Date: "2026-10-18T07:00:00Z", Timezone: "Europe/Rome"}},
+		{ID: "allday", Due: &todoist.Due{Date: "2026-10-18"}},
+		{ID: "yesterday", Due: &todoist.Due{Date: "2026-10-17T21:30:00Z", Timezone: "UTC"}},
+	}}
+	// Just past midnight in Rome, still the 18th in UTC
+	now := time.Date(2026, 10, 19, 0, 15, 0, 0, rome)
+
+	ids := func(tasks []todoist.Task) []string {
+		var result []string
+		for _, t := range tasks {
+			result = append(result, t.ID)
+		}
+		return result
+	}
+
+	today, _ := selectTasks(data, "today", now, 0)
+	if got := strings.Join(ids(today), ","); got != "fixed" {
+		t.Errorf("today = %s, want fixed", got)
+	}
+	due, _ := selectTasks(data, "due", now, 0)
+	if got := strings.Join(ids(due), ","); got != "yesterday,allday,morning,late" {
+		t.Errorf("due = %s, want yesterday,allday,morning,late", got)
+	}
+}
+
+func TestDescribeDue(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	now := time.Date(2026, 10, 18, 13, 45, 0, 0, rome)
+
+	tests := []struct {
+		due  todoist.Due
+		want string
+	}{
+		{todoist.Due{Date: "2026-10-18"}, "DUE TODAY"},
+		{todoist.Due{Date: "2026-10-18T14:30:00"}, "in 45 min ⏰"},
+		{todoist.Due{Date: "2026-10-18T13:45:10"}, "now ⏰"},
+		{todoist.Due{Date: "2026-10-18T18:00:00"}, "at 18:00"},
+		{todoist.Due{Date: "2026-10-18T16:00:00Z", Timezone: "UTC"}, "at 18:00"},
+		{todoist.Due{Date: "2026-10-18T13:15:00"}, "30 min overdue❗"},
+		{todoist.Due{Date: "2026-10-18T11:30:00"}, "2h overdue❗"},
+		{todoist.Due{Date: "2026-10-17T22:00:00"}, "15h overdue❗"},
+		{todoist.Due{Date: "2026-10-17"}, "1 day overdue❗"},
+		{todoist.Due{Date: "2026-10-16T09:00:00"}, "2 days overdue❗"},
+		{todoist.Due{Date: "2026-10-20T09:00:00"}, "due in 2 days at 09:00 ⚠️"},
+		{todoist.Due{Date: "2026-10-19"}, "due in 1 day ⚠️"},
+	}
+	for _, tt := range tests {
+		if got := describeDue(&tt.due, now); got != tt.want {
+			t.Errorf("describeDue(%s) = %q, want %q", tt.due.Date, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTasks_Now(t *testing.T) {
+	now := time.Now()
+	// The now mode starts at midnight, so keep both tasks on today
+	if now.Add(5*time.Hour).Format("2006-01-02") != now.Format("2006-01-02") {
+		t.Skip("too close to midnight")
+	}
+	s, srv := newTestService(t)
+	soon := now.Add(30 * time.Minute).Format("2006-01-02T15:04:05")
+	later := now.Add(5 * time.Hour).Format("2006-01-02T15:04:05")
+	srv.AddTask(todoist.Task{ID: "t4", Content: "standup", ProjectID: "p1", Priority: 1,
+		Due: &todoist.Due{Date: soon}})
+	srv.AddTask(todoist.Task{ID: "t5", Content: "deploy", ProjectID: "p1", Priority: 1,
+		Due: &todoist.Due{Date: later}})
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "t4"},
+		{"6h", "t4,t5"},
+		{"6h deploy", "t5"},
+		{"1h deploy", ""},
+	}
+	for _, tt := range tests {
+		out, err := s.QueryTasks("now", tt.input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", tt.input, err)
+		}
+		var ids []string
+		for _, item := range out.Items {
+			if id, ok := item.Variables["myTaskID"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("QueryTasks(now, %q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTasks_Upcoming(t *testing.T) {
+	s, srv := newTestService(t)
+	now := time.Now()
+	inTwo := now.AddDate(0, 0, 2)
+	srv.AddTask(todoist.Task{ID: "t4", Content: "book flights", ProjectID: "p2", Priority: 1,
+		Due: &todoist.Due{Date: inTwo.Format("2006-01-02")}})
+	srv.AddTask(todoist.Task{ID: "t5", Content: "renew passport", ProjectID: "p2", Priority: 1,
+		Due: &todoist.Due{Date: now.AddDate(0, 0, 10).Format("2006-01-02")}})
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// Separators show as their title, tasks as their ID
+	list := func(input string) string {
+		out, err := s.QueryTasks("upcoming", input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", input, err)
+		}
+		var entries []string
+		for _, item := range out.Items {
+			if id, ok := item.Variables["myTaskID"].(string); ok {
+				entries = append(entries, id)
+			} else {
+				entries = append(entries, item.Title)
+			}
+		}
+		return strings.Join(entries, "|")
+	}
+
+	todayHead := now.Format("Mon Jan 2")
+	twoHead := inTwo.Format("Mon Jan 2")
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", todayHead + " — 2 tasks|t1|t2|" + twoHead + " — 1 task|t4"},
+		{"@waiting ", todayHead + " — 1 task|t1"},
+		{"2d", todayHead + " — 2 tasks|t1|t2"},
+		{"14d #Home ", todayHead + " — 1 task|t2|" + twoHead + " — 1 task|t4|" +
+			now.AddDate(0, 0, 10).Format("Mon Jan 2") + " — 1 task|t5"},
+		{"2", todayHead + " — 2 tasks|t1|t2"},
+		{"14 #Home ", todayHead + " — 1 task|t2|" + twoHead + " — 1 task|t4|" +
+			now.AddDate(0, 0, 10).Format("Mon Jan 2") + " — 1 task|t5"},
+		// Only a leading number is a look-ahead
+		{"#Home 14", "no tasks matching your query 🙁"},
+	}
+	for _, tt := range tests {
+		if got := list(tt.input); got != tt.want {
+			t.Errorf("upcoming %q:\n got %s\nwant %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTasks_Filter(t *testing.T) {
+	s, _ := newTestService(t)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"today & !@waiting", "t2"},
+		{"(today | no date) & #Work", "t1,t3"},
+		{"no date", "t3"},
+		{"p1", ""},
+	}
+	for _, tt := range tests {
+		out, err := s.QueryTasks("filter", tt.input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", tt.input, err)
+		}
+		var ids []string
+		for _, item := range out.Items {
+			if id, ok := item.Variables["myTaskID"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("filter %q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	out, err := s.QueryTasks("filter", "today &")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if len(out.Items) != 1 || !strings.HasPrefix(out.Items[0].Title, "invalid filter") {
+		t.Errorf("invalid filter should give one error item, got %+v", out.Items)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	s, srv := newTestService(t)
+	srv.AddFilter(todoist.Filter{Name: "Work today", Query: "today & #Work", ItemOrder: 1})
+	srv.AddFilter(todoist.Filter{Name: "Broken", Query: "today &", ItemOrder: 2})
+	srv.AddFilter(todoist.Filter{Name: "Waiting", Query: "@waiting", ItemOrder: 3, IsFavorite: true})
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	titles := func(out *alfred.Output) string {
+		var result []string
+		for _, item := range out.Items {
+			result = append(result, item.Title)
+		}
+		return strings.Join(result, "|")
+	}
+
+	out, err := s.Filters("")
+	if err != nil {
+		t.Fatalf("Filters: %v", err)
+	}
+	if got, want := titles(out), "⭐ Waiting (1)|Work today (1)|Broken (?)"; got != want {
+		t.Errorf("Filters() = %s, want %s", got, want)
+	}
+	if out, _ = s.Filters("work"); titles(out) != "Work today (1)" {
+		t.Errorf("Filters(work) = %s, want Work today (1)", titles(out))
+	}
+
+	// !name in a query offers the saved filter, which runs in filter mode
+	out, err = s.QueryTasks("today", "!wait")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if len(out.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(out.Items))
+	}
+	if got := titles(out); got != "⭐ Waiting" {
+		t.Errorf("QueryTasks(!wait) = %s, want ⭐ Waiting without a count", got)
+	}
+	vars := out.Items[0].Variables
+	if vars["myMode"] != "filter" || vars["myArg"] != "@waiting" {
+		t.Errorf("variables = %v, want filter mode with @waiting", vars)
+	}
+
+	// Only a leading ! picks a saved filter; later it's searched for
+	out, err = s.QueryTasks("all", "report !wait")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if got := titles(out); got != "no tasks matching your query 🙁" {
+		t.Errorf("QueryTasks(report !wait) = %s, want no matching tasks", got)
+	}
+}
+
+func TestQueryTasks_NegationAndAlternatives(t *testing.T) {
+	s, _ := newTestService(t)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"#Work -@waiting ", "t3"},
+		{"-#Work ", "t2"},
+		{"-milk", "t1,t3"},
+		{"#Work|#Home -report", "t2,t3"},
+		{"report|milk", "t1,t2"},
+		{"@waiting|#Home ", "t1,t2"},
+		{"-@waiting|#Home ", "t3"},
+	}
+	for _, tt := range tests {
+		out, err := s.QueryTasks("all", tt.input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", tt.input, err)
+		}
+		var ids []string
+		for _, item := range out.Items {
+			if id, ok := item.Variables["myTaskID"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("all %q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	// Completing a negated or alternative fragment keeps what precedes it
+	completions := []struct {
+		input string
+		want  string
+	}{
+		{"-@wai", "-@waiting "},
+		{"report -@wai", "report -@waiting "},
+		{"#Home|#Wo", "#Home|#Work "},
+	}
+	for _, tt := range completions {
+		out, err := s.QueryTasks("all", tt.input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", tt.input, err)
+		}
+		if len(out.Items) != 1 || out.Items[0].Variables["myArg"] != tt.want {
+			t.Errorf("completing %q: got %+v, want one item with myArg %q", tt.input, out.Items, tt.want)
+		}
+	}
+}
+
+func TestQueryTasks_PriorityAndDateTokens(t *testing.T) {
+	s, srv := newTestService(t)
+	now := time.Now()
+	srv.AddTask(todoist.Task{ID: "t4", Content: "ship release", ProjectID: "p1", Priority: 4,
+		Due:      &todoist.Due{Date: now.AddDate(0, 0, 3).Format("2006-01-02")},
+		Deadline: &todoist.Deadline{Date: now.AddDate(0, 0, 1).Format("2006-01-02")}})
+	srv.AddTask(todoist.Task{ID: "t5", Content: "plan offsite", ProjectID: "p1", Priority: 3})
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"p1", "t4"},
+		{"p4", "t1,t2,t3"},
+		{"p<3", "t4,t5"},
+		{"p>=3", "t1,t2,t3"},
+		{"p1|p2", "t4,t5"},
+		{"-p4", "t4,t5"},
+		{"due:<2d", "t1,t2"},
+		{"due:today..5d", "t1,t2,t4"},
+		{"due:>today", "t4"},
+		{"nodue", "t3,t5"},
+		{"-nodue p4", "t1,t2"},
+		{"deadline:<=tomorrow", "t4"},
+		{"nodeadline p1", ""},
+		{"due:bogus", ""},
+	}
+	for _, tt := range tests {
+		out, err := s.QueryTasks("all", tt.input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", tt.input, err)
+		}
+		var ids []string
+		for _, item := range out.Items {
+			if id, ok := item.Variables["myTaskID"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("all %q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	// Natural language dates are in the configured language
+	s.cfg.DueLang = "it"
+	out, err := s.QueryTasks("all", "deadline:domani")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if len(out.Items) != 1 || out.Items[0].Variables["myTaskID"] != "t4" {
+		t.Errorf("deadline:domani = %+v, want t4", out.Items)
+	}
+}
+
+func TestQueryTasks_FuzzyRanking(t *testing.T) {
+	s, srv := newTestService(t)
+	srv.AddTask(todoist.Task{ID: "t4", Content: "Meeting preparation", ProjectID: "p1", Priority: 1})
+	srv.AddTask(todoist.Task{ID: "t5", Content: "Prep for mtg with Ann", ProjectID: "p1", Priority: 4,
+		Due: &todoist.Due{Date: time.Now().Format("2006-01-02")}})
+	srv.AddTask(todoist.Task{ID: "t6", Content: "Book café", ProjectID: "p2", Priority: 1})
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"mtg prep", "t5,t4"},
+		{"meeting", "t4"},
+		{"wrte reprot", "t1"},
+		{"cafe", "t6"},
+		{"CAFÉ", "t6"},
+		// Without free text the all mode keeps its due date order
+		{"#Work ", "t1,t5,t3,t4"},
+	}
+	for _, tt := range tests {
+		out, err := s.QueryTasks("all", tt.input)
+		if err != nil {
+			t.Fatalf("QueryTasks(%q): %v", tt.input, err)
+		}
+		var ids []string
+		for _, item := range out.Items {
+			if id, ok := item.Variables["myTaskID"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("all %q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTasks_StaleCacheRefreshesInBackground(t *testing.T) {
+	s, srv := newTestService(t)
+	s.cache.Invalidate()
+	started := 0
+	s.startRefresh = func() error {
+		started++
+		return nil
+	}
+	srv.AddTask(todoist.Task{ID: "t4", Content: "new task", ProjectID: "p1",
+		Due: &todoist.Due{Date: time.Now().Format("2006-01-02")}})
+
+	out, err := s.QueryTasks("today", "")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if started != 1 {
+		t.Errorf("background refresh started %d times, want once", started)
+	}
+	if n := len(srv.SyncTokens()); n != 1 {
+		t.Errorf("query synced %d times, want it to answer from the cache", n-1)
+	}
+	if len(out.Items) != 2 {
+		t.Errorf("got %d items, want the 2 cached tasks", len(out.Items))
+	}
+	if !strings.Contains(out.Items[0].Subtitle, "🔄 synced just now") {
+		t.Errorf("subtitle = %q, want the sync status", out.Items[0].Subtitle)
+	}
+
+	// While the refresh runs, Alfred is asked to rerun the query
+	pidfile := filepath.Join(s.cfg.DataFolder, "refresh.pid")
+	os.WriteFile(pidfile, []byte(fmt.Sprint(os.Getpid())), 0644)
+	out, err = s.QueryTasks("today", "")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if out.Rerun == 0 || !strings.Contains(out.Items[0].Subtitle, "syncing") {
+		t.Errorf("rerun = %v, subtitle = %q; want a rerun while syncing", out.Rerun, out.Items[0].Subtitle)
+	}
+
+	if err := s.BackgroundRefresh(); err != nil {
+		t.Fatalf("BackgroundRefresh: %v", err)
+	}
+	if _, err := os.Stat(pidfile); !os.IsNotExist(err) {
+		t.Error("pidfile should be removed once the refresh is done")
+	}
+	out, _ = s.QueryTasks("today", "")
+	if len(out.Items) != 3 || out.Rerun != 0 {
+		t.Errorf("got %d items, rerun %v; want the 3 refreshed tasks and no rerun", len(out.Items), out.Rerun)
+	}
+}
+
+func TestQueryTasks_StaleCacheFallsBackToSync(t *testing.T) {
+	s, srv := newTestService(t)
+	s.cache.Invalidate()
+	s.startRefresh = func() error { return errors.New("cannot start") }
+
+	if _, err := s.QueryTasks("today", ""); err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if n := len(srv.SyncTokens()); n != 2 {
+		t.Errorf("%d syncs, want the query to refresh itself", n)
+	}
+}
+
+func TestSyncStatus(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago        time.Duration
+		refreshing bool
+		want       string
+	}{
+		{20 * time.Second, false, "🔄 synced just now"},
+		{5 * time.Minute, false, "🔄 synced 5 min ago"},
+		{3*time.Hour + 10*time.Minute, false, "🔄 synced 3h ago"},
+		{50 * time.Hour, false, "🔄 synced 2 days ago"},
+		{5 * time.Minute, true, "🔄 syncing… (last 5 min ago)"},
+	}
+	for _, tt := range tests {
+		if got := syncStatus(now.Add(-tt.ago), now, tt.refreshing); got != tt.want {
+			t.Errorf("syncStatus(%v, %v) = %q, want %q", tt.ago, tt.refreshing, got, tt.want)
+		}
+	}
+	if got := syncStatus(time.Time{}, now, false); got != "" {
+		t.Errorf("syncStatus(zero) = %q, want none", got)
+	}
+}
+
+func TestQueryTasks_ModeMaxAge(t *testing.T) {
+	s, srv := newTestService(t)
+	s.cfg.RefreshRate = time.Hour
+	s.cfg.ModeMaxAge = map[string]time.Duration{"today": 0}
+
+	// all is within the refresh rate and answers from the cache
+	if _, err := s.QueryTasks("all", ""); err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if n := len(srv.SyncTokens()); n != 1 {
+		t.Errorf("all synced %d times, want none", n-1)
+	}
+
+	// today is always fresh, waiting for the sync
+	srv.AddTask(todoist.Task{ID: "t4", Content: "new task", ProjectID: "p1",
+		Due: &todoist.Due{Date: time.Now().Format("2006-01-02")}})
+	out, err := s.QueryTasks("today", "")
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if n := len(srv.SyncTokens()); n != 2 {
+		t.Errorf("today synced %d times, want once", n-1)
+	}
+	if len(out.Items) != 3 {
+		t.Errorf("got %d items, want the new task too", len(out.Items))
+	}
+}
+
+func TestCompleteTask_InvalidatesCacheWhenRefreshFails(t *testing.T) {
+	s, srv := newTestService(t)
+
+	// The close goes through, the syncs after it don't
+	srv.FailNext(0, http.StatusInternalServerError, http.StatusInternalServerError,
+		http.StatusInternalServerError, http.StatusInternalServerError)
+	if _, err := s.CompleteTask("t2"); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if !s.cache.NeedsRefresh() {
+		t.Error("the cache should be invalidated when refreshing after a change fails")
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"alfredo-go/pkg/cache"
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/utils"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// journal records the prior state of the tasks touched by a mutation once it
+// went through (or was queued), and passes err on unchanged
+func (s *TaskService) journal(action string, prior []todoist.Task, err error) error {
+	return s.journalEntry(cache.UndoEntry{Action: action, Tasks: prior}, err)
+}
+
+// journalEntry is journal for an entry with more to it than the prior tasks
+func (s *TaskService) journalEntry(entry cache.UndoEntry, err error) error {
+	if len(entry.Tasks) == 0 || (err != nil && !errors.Is(err, ErrQueued)) {
+		return err
+	}
+	entry.At = time.Now()
+	if jErr := s.cache.PushUndo(entry); jErr != nil {
+		utils.Log("warning: could not record undo entry: %v", jErr)
+	}
+	return err
+}
+
+// priorTasks returns the cached state of the given tasks, skipping unknown IDs
+func (s *TaskService) priorTasks(ids ...string) []todoist.Task {
+	var tasks []todoist.Task
+	for _, id := range ids {
+		if t, ok := s.cache.Task(id); ok {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// Undo reverts the most recent journaled mutation: completed tasks are
+// reopened, deleted tasks are recreated with their original fields, and
+// anything else gets its previous content, labels, priority, dates and
+// project back. Returns a summary for the notification, and a warning about
+// what couldn't be restored, if anything.
+func (s *TaskService) Undo() (summary, warning string, err error) {
+	entries, err := s.cache.LoadUndo()
+	if err != nil {
+		return "", "", err
+	}
+	if len(entries) == 0 {
+		return "", "", nil
+	}
+	last := entries[len(entries)-1]
+
+	var cmds []todoist.Command
+	for _, t := range last.Tasks {
+		switch {
+		case last.Action == "complete" && !t.Recurring():
+			cmds = append(cmds, todoist.ItemUncomplete(t.ID))
+		case last.Action == "delete":
+			cmds = append(cmds, recreateCommand(t))
+		default:
+			// Completing a recurring task only moves its due date
+			current, known := s.cache.Task(t.ID)
+			cmds = append(cmds, restoreCommands(t, current, known)...)
+		}
+	}
+
+	err = s.submit(cmds, func() error {
+		return s.sendCommands(cmds)
+	})
+	if err != nil && !errors.Is(err, ErrQueued) {
+		return "", "", err
+	}
+	if sErr := s.cache.RemoveUndo(last); sErr != nil {
+		utils.Log("warning: could not update undo journal: %v", sErr)
+	}
+
+	what := fmt.Sprintf("%d tasks", len(last.Tasks))
+	if len(last.Tasks) == 1 {
+		what = "'" + last.Tasks[0].Content + "'"
+	}
+	var lost []string
+	if last.Subtasks > 0 {
+		lost = append(lost, fmt.Sprintf("%d %s", last.Subtasks, pluralize(last.Subtasks, "subtask", "subtasks")))
+	}
+	if last.Comments > 0 {
+		lost = append(lost, fmt.Sprintf("%d %s", last.Comments, pluralize(last.Comments, "comment", "comments")))
+	}
+	if len(lost) > 0 {
+		warning = strings.Join(lost, " and ") + " couldn't be restored"
+	}
+	return fmt.Sprintf("undid %s of %s", last.Action, what), warning, err
+}
+
+// restoreCommands puts a task's editable fields back, and moves it back if
+// current, its state now, is somewhere else
+func restoreCommands(t, current todoist.Task, known bool) []todoist.Command {
+	updates := map[string]any{
+		"content":     t.Content,
+		"description": t.Description,
+		"labels":      t.Labels,
+		"priority":    t.Priority,
+		"due":         dueArgs(t.Due),
+		"deadline":    deadlineArgs(t.Deadline),
+	}
+	cmds := []todoist.Command{todoist.ItemUpdate(t.ID, updates)}
+	switch {
+	case known && current.ProjectID == t.ProjectID && current.SectionID == t.SectionID && current.ParentID == t.ParentID:
+		// still in place
+	case t.ParentID != "":
+		cmds = append(cmds, todoist.ItemMoveUnder(t.ID, t.ParentID))
+	default:
+		cmds = append(cmds, todoist.ItemMove(t.ID, t.ProjectID, t.SectionID))
+	}
+	return cmds
+}
+
+// recreateCommand adds a deleted task again with its original fields
+func recreateCommand(t todoist.Task) todoist.Command {
+	args := map[string]any{
+		"content":  t.Content,
+		"labels":   t.Labels,
+		"priority": t.Priority,
+	}
+	if t.Description != "" {
+		args["description"] = t.Description
+	}
+	if t.ProjectID != "" {
+		args["project_id"] = t.ProjectID
+	}
+	if t.SectionID != "" {
+		args["section_id"] = t.SectionID
+	}
+	if t.ParentID != "" {
+		args["parent_id"] = t.ParentID
+	}
+	if due := dueArgs(t.Due); due != nil {
+		args["due"] = due
+	}
+	if deadline := deadlineArgs(t.Deadline); deadline != nil {
+		args["deadline"] = deadline
+	}
+	return todoist.ItemAdd(todoist.NewUUID(), args)
+}
+
+// dueArgs converts a cached due date back into Sync API arguments, keeping
+// the recurrence rule of recurring tasks
+func dueArgs(d *todoist.Due) any {
+	if d == nil || d.Date == "" {
+		return nil
+	}
+	return todoist.Task{Due: d}.Reschedule(d.Date)
+}
+
+func deadlineArgs(d *todoist.Deadline) any {
+	if d == nil || d.Date == "" {
+		return nil
+	}
+	return map[string]string{"date": d.Date}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"alfredo-go/pkg/todoist"
+	"strings"
+	"testing"
+)
+
+func TestDeleteAndUndo(t *testing.T) {
+	s, srv := newTestService(t)
+
+	if err := s.DeleteTask("t2"); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if task, _ := srv.Task("t2"); !task.IsDeleted {
+		t.Fatal("task was not deleted on the server")
+	}
+
+	summary, warning, err := s.Undo()
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if !strings.Contains(summary, "buy milk") || warning != "" {
+		t.Errorf("summary = %q, warning = %q, want the task named and no warning", summary, warning)
+	}
+	var recreated bool
+	for _, task := range srv.ActiveTasks() {
+		if task.Content == "buy milk" && task.ProjectID == "p2" {
+			recreated = true
+		}
+	}
+	if !recreated {
+		t.Error("deleted task was not recreated")
+	}
+}
+
+func TestUndo_DeleteWarnsAboutLostSubtasksAndComments(t *testing.T) {
+	s, _ := newTestService(t)
+
+	if err := s.DeleteTask("t1"); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	_, warning, err := s.Undo()
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if warning != "1 subtask and 1 comment couldn't be restored" {
+		t.Errorf("warning = %q", warning)
+	}
+}
+
+func TestUndo_Complete(t *testing.T) {
+	s, srv := newTestService(t)
+
+	if _, err := s.CompleteTask("t2"); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if _, _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if task, _ := srv.Task("t2"); task.Checked {
+		t.Error("t2 should be open again")
+	}
+	if _, ok := s.cache.Task("t2"); !ok {
+		t.Error("reopened task should be back in the cache")
+	}
+}
+
+func TestUndo_CompleteRecurring(t *testing.T) {
+	s, srv := newTestService(t)
+	srv.AddTask(todoist.Task{ID: "r1", Content: "water plants", Priority: 1,
+		Due: &todoist.Due{Date: "2026-10-21", String: "every week", Lang: "en", IsRecurring: true}})
+	if err := s.cache.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.CompleteTask("r1"); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if task, _ := srv.Task("r1"); task.Due == nil || task.Due.Date != "2026-10-28" {
+		t.Fatalf("due after completing = %+v, want the next occurrence", task.Due)
+	}
+	if _, _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	task, _ := srv.Task("r1")
+	if task.Checked || task.Due == nil || task.Due.Date != "2026-10-21" || !task.Due.IsRecurring || task.Due.String != "every week" {
+		t.Errorf("task after undo = %+v / %+v, want it back on 2026-10-21, still recurring", task, task.Due)
+	}
+}
+
+func TestUndo_Reschedule(t *testing.T) {
+	s, srv := newTestService(t)
+	before, _ := srv.Task("t2")
+
+	if err := s.RescheduleTask("t2", "2026-11-01"); err != nil {
+		t.Fatalf("RescheduleTask: %v", err)
+	}
+	if _, _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if task, _ := srv.Task("t2"); task.Due == nil || task.Due.Date != before.Due.Date {
+		t.Errorf("due after undo = %+v, want %s", task.Due, before.Due.Date)
+	}
+}
+
+func TestUndo_Edit(t *testing.T) {
+	s, srv := newTestService(t)
+
+	desc := "soy"
+	if err := s.EditTask("t2", "buy soy milk", "waiting", "p1", "", "", "", "", "", 4, "", "", &desc); err != nil {
+		t.Fatalf("EditTask: %v", err)
+	}
+	if _, _, err := s.Undo(); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	task, _ := srv.Task("t2")
+	if task.Content != "buy milk" || task.Description != "oat, not cow" || len(task.Labels) != 0 ||
+		task.Priority != 1 || task.ProjectID != "p2" || task.Due == nil {
+		t.Errorf("task after undo = %+v, want it as before the edit", task)
+	}
+}
+
+func TestUndo_Bulk(t *testing.T) {
+	s, srv := newTestService(t)
+
+	if _, err := s.BulkApply("today", "", "move:#Home"); err != nil {
+		t.Fatalf("BulkApply: %v", err)
+	}
+	summary, _, err := s.Undo()
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if !strings.Contains(summary, "2 tasks") {
+		t.Errorf("summary = %q, want both tasks counted", summary)
+	}
+	if task, _ := srv.Task("t1"); task.ProjectID != "p1" {
+		t.Errorf("t1 project = %s, want it back in p1", task.ProjectID)
+	}
+	if task, _ := srv.Task("t2"); task.ProjectID != "p2" {
+		t.Errorf("t2 project = %s, want it to stay in p2", task.ProjectID)
+	}
+}
+
+func TestRestoreCommands_MovesOnlyIfMoved(t *testing.T) {
+	prior := todoist.Task{ID: "t1", Content: "write report", ProjectID: "p1", SectionID: "s1"}
+
+	types := func(cmds []todoist.Command) string {
+		var result []string
+		for _, cmd := range cmds {
+			result = append(result, cmd.Type)
+		}
+		return strings.Join(result, ",")
+	}
+	moved := prior
+	moved.ProjectID, moved.SectionID = "p2", ""
+	subtask := prior
+	subtask.ParentID = "t9"
+
+	tests := []struct {
+		name    string
+		current todoist.Task
+		known   bool
+		want    string
+	}{
+		{"in place", prior, true, "item_update"},
+		{"moved", moved, true, "item_update,item_move"},
+		{"made a subtask", subtask, true, "item_update,item_move"},
+		{"not cached", todoist.Task{}, false, "item_update,item_move"},
+	}
+	for _, tt := range tests {
+		if got := types(restoreCommands(prior, tt.current, tt.known)); got != tt.want {
+			t.Errorf("%s: commands = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import "encoding/json"
 
 // Output represents the Alfred workflow output format
 type Output struct {
+	// Rerun asks Alfred to run the script filter again after this many
+	// seconds (0.1 to 5), e.g. to pick up a refresh running in the background
+	Rerun float64      `json:"rerun,omitempty"`
 	Items []OutputItem `json:"items"`
 }
 
@@ -15,6 +18,13 @@ type OutputItem struct {
 	Variables map[string]any      `json:"variables,omitempty"`
 	Mods      map[string]ModsItem `json:"mods,omitempty"`
 	Icon      *Icon               `json:"icon,omitempty"`
+	Text      *Text               `json:"text,omitempty"`
+}
+
+// Text is what Alfred copies (⌘C) or shows in Large Type (⌘L) for an item
+type Text struct {
+	Copy      string `json:"copy,omitempty"`
+	LargeType string `json:"largetype,omitempty"`
 }
 
 // ModsItem represents modifier keys in Alfred workflow
@@ -33,3 +43,15 @@ type Icon struct {
 func (o *Output) Marshal() ([]byte, error) {
 	return json.Marshal(o)
 }
+
+// WorkflowOutput is the JSON a Run Script action prints to pass its argument
+// and variables on to the next workflow objects
+type WorkflowOutput struct {
+	Arg       string         `json:"arg"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// Marshal wraps the output in the top-level "alfredworkflow" object Alfred expects
+func (w *WorkflowOutput) Marshal() ([]byte, error) {
+	return json.Marshal(map[string]*WorkflowOutput{"alfredworkflow": w})
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"alfredo-go/pkg/utils"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBackgroundAge is how long a background refresh may run before its
+// pidfile is ignored, in case the process died or its PID was reused
+const maxBackgroundAge = 5 * time.Minute
+
+// claimGrace is how long an empty pidfile counts as being claimed; one left
+// empty longer is from a process that died before writing the PID
+const claimGrace = 10 * time.Second
+
+func (c *Cache) pidPath() string {
+	return filepath.Join(c.cfg.DataFolder, "refresh.pid")
+}
+
+// pidLockPath is locked while the pidfile is claimed or removed. It is not
+// the cache lock, which a refresh holds for as long as it syncs.
+func (c *Cache) pidLockPath() string {
+	return filepath.Join(c.cfg.DataFolder, "refresh.pid.lock")
+}
+
+// StartBackgroundRefresh runs this executable again with args, detached, to
+// refresh the cache while the current process answers from the stale copy.
+// It returns false without starting anything if a background refresh is
+// already running. The refresh process calls EndBackgroundRefresh when done.
+func (c *Cache) StartBackgroundRefresh(args ...string) (bool, error) {
+	if c.cfg.DataFolder == "" {
+		return false, errors.New("no data folder to refresh into")
+	}
+	if c.RefreshRunning() {
+		return false, nil
+	}
+	// Queries started at the same time check and claim the pidfile one at a
+	// time, and the refresh can't end before its PID is written
+	lock, err := acquireLock(c.pidLockPath(), true)
+	if err != nil {
+		return false, err
+	}
+	defer lock.Unlock()
+	if c.RefreshRunning() {
+		return false, nil
+	}
+	// Whatever is left is from a refresh that died
+	if err := os.Remove(c.pidPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	f, err := os.OpenFile(c.pidPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		os.Remove(c.pidPath())
+		return false, err
+	}
+	// No stdin/stdout/stderr: Alfred waits for the script filter's output to
+	// be closed, so the refresh mustn't inherit it
+	cmd := exec.Command(exe, args...)
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		os.Remove(c.pidPath())
+		return false, err
+	}
+	if _, err := fmt.Fprintf(f, "%d\n", cmd.Process.Pid); err != nil {
+		utils.Log("warning: could not write the background refresh PID: %v", err)
+	}
+	return true, cmd.Process.Release()
+}
+
+// RefreshRunning reports whether a background refresh is in progress
+func (c *Cache) RefreshRunning() bool {
+	if c.cfg.DataFolder == "" {
+		return false
+	}
+	info, err := os.Stat(c.pidPath())
+	if err != nil || time.Since(info.ModTime()) > maxBackgroundAge {
+		return false
+	}
+	b, err := os.ReadFile(c.pidPath())
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		// Claimed, but the process hasn't been started yet
+		return time.Since(info.ModTime()) < claimGrace
+	}
+	return processAlive(pid)
+}
+
+// EndBackgroundRefresh removes the pidfile of the background refresh
+// running in this process
+func (c *Cache) EndBackgroundRefresh() {
+	// Wait for the process that started us to write our PID
+	if lock, err := acquireLock(c.pidLockPath(), true); err == nil {
+		defer lock.Unlock()
+	}
+	b, err := os.ReadFile(c.pidPath())
+	if err != nil {
+		return
+	}
+	if pid, _ := strconv.Atoi(strings.TrimSpace(string(b))); pid == os.Getpid() {
+		os.Remove(c.pidPath())
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package cache
+
+import (
+	"os"
+	"os/exec"
+)
+
+// detach is a no-op: a started process already outlives its parent here
+func detach(cmd *exec.Cmd) {}
+
+// processAlive reports whether a process with this PID exists
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
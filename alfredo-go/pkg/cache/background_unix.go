@@ -0,0 +1,20 @@
+//go:build unix
+
+package cache
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// detach starts cmd in a session of its own so it outlives Alfred's script
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// processAlive reports whether a process with this PID exists
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -4,7 +4,12 @@ import (
 	"alfredo-go/pkg/config"
 	"alfredo-go/pkg/todoist"
 	"alfredo-go/pkg/utils"
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
@@ -12,20 +17,27 @@ import (
 
 // CachedData holds all cached Todoist data
 type CachedData struct {
-	Tasks     []todoist.Task       `json:"tasks"`
-	Projects  []todoist.Project    `json:"projects"`
-	Sections  []todoist.Section    `json:"sections"`
-	Labels    []todoist.Label      `json:"labels"`
+	Tasks     []todoist.Task         `json:"tasks"`
+	Projects  []todoist.Project      `json:"projects"`
+	Sections  []todoist.Section      `json:"sections"`
+	Labels    []todoist.Label        `json:"labels"`
+	Notes     []todoist.Note         `json:"notes,omitempty"`
+	Filters   []todoist.Filter       `json:"filters,omitempty"`
 	Stats     *todoist.StatsResponse `json:"stats"`
-	User      *todoist.UserInfo    `json:"user"`
-	FetchedAt time.Time            `json:"fetched_at"`
+	User      *todoist.UserInfo      `json:"user"`
+	SyncToken string                 `json:"sync_token,omitempty"`
+	FetchedAt time.Time              `json:"fetched_at"`
+
+	index *Index
 }
 
 // Cache manages local caching of Todoist data
 type Cache struct {
-	client *todoist.Client
-	cfg    *config.Config
-	data   *CachedData
+	client   *todoist.Client
+	cfg      *config.Config
+	data     *CachedData
+	raw      []byte // gob payload checked by Load, decoded on first use
+	fullSync bool   // set by ResetSyncToken: ignore the saved sync token
 }
 
 // NewCache creates a new Cache
@@ -37,7 +49,7 @@ func NewCache(client *todoist.Client, cfg *config.Config) *Cache {
 }
 
 func (c *Cache) dbPath() string {
-	return filepath.Join(c.cfg.DataFolder, "allData.json")
+	return c.dbPathFor(c.cfg.CacheFormat)
 }
 
 func (c *Cache) labelCountsPath() string {
@@ -50,36 +62,109 @@ func (c *Cache) projectCountsPath() string {
 
 // NeedsRefresh returns true if the cache is stale or missing
 func (c *Cache) NeedsRefresh() bool {
+	return c.OlderThan(c.cfg.RefreshRate)
+}
+
+// OlderThan returns true if the cache was saved at least maxAge ago, or is
+// missing. A maxAge of 0 always needs a refresh.
+func (c *Cache) OlderThan(maxAge time.Duration) bool {
 	if c.cfg.DataFolder == "" {
 		return true
 	}
-	info, err := os.Stat(c.dbPath())
+	_, info, err := c.statDB()
 	if err != nil {
 		return true
 	}
-	elapsed := time.Since(info.ModTime())
-	return elapsed.Hours() >= float64(c.cfg.RefreshRate*24)
+	return time.Since(info.ModTime()) >= maxAge
+}
+
+// Invalidate marks the cache as stale so that the next query refreshes it,
+// e.g. when a change went through but refreshing right after it failed
+func (c *Cache) Invalidate() error {
+	if c.cfg.DataFolder == "" {
+		return nil
+	}
+	path, _, err := c.statDB()
+	if err == nil {
+		err = os.Chtimes(path, time.Time{}, time.Unix(0, 0))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
 }
 
-// Refresh fetches all data from the API in a single sync call and saves to disk
+// Refresh syncs with the API and saves to disk. When a sync token from a
+// previous run is available only the changes since then are requested and
+// merged into the cached data; otherwise (or if the incremental sync fails)
+// everything is downloaded again.
+//
+// Alfred often runs several of us at once, so only one process syncs at a
+// time: if another one already is, the last snapshot it saved is used
+// instead.
 func (c *Cache) Refresh() error {
+	if c.cfg.DataFolder != "" {
+		lock, err := acquireLock(c.lockPath(), false)
+		if errors.Is(err, errLocked) {
+			if err := c.Load(); err == nil {
+				utils.Log("another process is refreshing, using the last snapshot")
+				return nil
+			}
+			// Nothing usable on disk yet: wait for the other sync to finish
+			if lock, err = acquireLock(c.lockPath(), true); err == nil {
+				defer lock.Unlock()
+				if err := c.Load(); err == nil {
+					return nil
+				}
+			}
+		} else if err == nil {
+			defer lock.Unlock()
+		}
+		if err != nil {
+			utils.Log("warning: could not lock the cache, refreshing anyway: %v", err)
+		}
+	}
+	return c.sync()
+}
+
+// sync does the actual refresh, with the lock held
+func (c *Cache) sync() error {
 	utils.Log("refreshing cache...")
 
-	syncResp, err := c.client.SyncAll()
-	if err != nil {
-		return err
+	// Apply the changes to what is on disk now, which another process may
+	// have saved since it was loaded
+	if c.cfg.DataFolder != "" {
+		if err := c.reload(); err != nil {
+			c.data = nil
+		}
 	}
 
-	c.data = &CachedData{
-		Tasks:     syncResp.Items,
-		Projects:  syncResp.Projects,
-		Sections:  syncResp.Sections,
-		Labels:    syncResp.Labels,
-		Stats:     syncResp.Stats,
-		User:      syncResp.User,
-		FetchedAt: time.Now(),
+	var syncResp *todoist.SyncAllResponse
+	var err error
+	if c.data != nil && c.data.SyncToken != "" && !c.fullSync {
+		syncResp, err = c.client.Sync(c.data.SyncToken)
+		if err != nil {
+			if !syncTokenRejected(err) {
+				return err
+			}
+			utils.Log("sync token rejected, falling back to full sync: %v", err)
+		}
+	}
+	if syncResp == nil {
+		syncResp, err = c.client.SyncAll()
+		if err != nil {
+			return err
+		}
+		// Full sync responses don't always set the flag; make it explicit
+		syncResp.FullSync = true
 	}
 
+	if c.data == nil {
+		c.data = &CachedData{}
+	}
+	c.data.applySync(syncResp)
+	c.data.FetchedAt = time.Now()
+
 	if c.data.User == nil {
 		c.data.User = &todoist.UserInfo{}
 	}
@@ -87,50 +172,210 @@ func (c *Cache) Refresh() error {
 	if err := c.save(); err != nil {
 		return err
 	}
+	c.fullSync = false
+	c.saveCounts()
 
-	// Save label and project counts
-	labelCounts := ComputeLabelCounts(c.data.Tasks, c.data.Labels)
-	if err := saveJSON(c.labelCountsPath(), labelCounts); err != nil {
-		utils.Log("warning: failed to save label counts: %v", err)
+	utils.Log("cache refreshed")
+	return nil
+}
+
+// syncTokenRejected reports whether Todoist refused the stored sync token,
+// the only incremental sync failure worth a full download. Network errors,
+// rate limits and server errors would fail the full sync just the same.
+func syncTokenRejected(err error) bool {
+	var apiErr *todoist.APIError
+	if !errors.As(err, &apiErr) {
+		return false
 	}
+	return apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusGone
+}
 
-	projectCounts := ComputeProjectCounts(c.data.Tasks, c.data.Projects, c.data.Sections)
-	if err := saveJSON(c.projectCountsPath(), projectCounts); err != nil {
-		utils.Log("warning: failed to save project counts: %v", err)
+// ResetSyncToken makes the next Refresh download everything again instead of
+// the changes since the stored sync token, discarding any local-only changes
+func (c *Cache) ResetSyncToken() {
+	c.fullSync = true
+}
+
+// Load reads cached data from disk. A gob file is only checked (header and
+// checksum) here and decoded when the data is first used. A file that can't
+// be read is moved aside (to allData.json.corrupt or allData.gob.corrupt) so
+// that the next refresh starts over with a full sync. If there is only a file
+// in the other format, it is converted.
+func (c *Cache) Load() error {
+	path := c.dbPath()
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c.migrate()
+	}
+	if err != nil {
+		return err
+	}
+	var data *CachedData
+	var raw []byte
+	if c.cfg.CacheFormat == config.CacheFormatGob {
+		raw, err = readGob(bufio.NewReader(f))
+	} else {
+		data, err = decodeFormat(f, c.cfg.CacheFormat)
+	}
+	f.Close()
+	if errors.Is(err, errUnsupportedVersion) {
+		return err
 	}
+	if err != nil {
+		moveAside(path)
+		return fmt.Errorf("corrupt cache file: %w", err)
+	}
+	c.data, c.raw = data, raw
+	return nil
+}
 
-	utils.Log("cache refreshed")
+// decode decodes the payload Load checked. A payload that doesn't decode
+// despite its checksum (written by a binary with other types) is moved aside
+// like a damaged file, and leaves no data: nothing is saved from it.
+func (c *Cache) decode() error {
+	if c.raw == nil {
+		return nil
+	}
+	data, err := decodeGob(c.raw)
+	c.raw = nil
+	if err != nil {
+		moveAside(c.dbPath())
+		return fmt.Errorf("corrupt cache file: %w", err)
+	}
+	c.data = data
 	return nil
 }
 
-// Load reads cached data from disk
-func (c *Cache) Load() error {
-	f, err := os.Open(c.dbPath())
+// moveAside keeps a cache file that can't be used for inspection, out of the
+// way of the next refresh
+func moveAside(path string) {
+	if err := os.Rename(path, path+".corrupt"); err != nil {
+		utils.Log("warning: could not move corrupt cache aside: %v", err)
+	}
+}
+
+// migrate converts the cached data file of the other format to the
+// configured one, keeping its modification time so that it is exactly as
+// fresh as before
+func (c *Cache) migrate() error {
+	old := c.dbPathFor(c.otherFormat())
+	f, err := os.Open(old)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	data, err := decodeFormat(f, c.otherFormat())
+	info, serr := f.Stat()
+	f.Close()
+	if err == nil {
+		err = serr
+	}
+	if err != nil {
+		return fmt.Errorf("migrating %s: %w", filepath.Base(old), err)
+	}
+
+	c.data = data
+	if err := c.save(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(c.dbPath(), time.Time{}, info.ModTime()); err != nil {
+		utils.Log("warning: could not keep the age of the migrated cache: %v", err)
+	}
+	if err := os.Remove(old); err != nil && !errors.Is(err, os.ErrNotExist) {
+		utils.Log("warning: could not remove %s: %v", filepath.Base(old), err)
+	}
+	utils.Log("migrated cache from %s to %s", filepath.Base(old), filepath.Base(c.dbPath()))
+	return nil
+}
 
-	c.data = &CachedData{}
-	return json.NewDecoder(f).Decode(c.data)
+// ensureData loads and decodes the cached data unless it already is
+func (c *Cache) ensureData() error {
+	if c.data == nil && c.raw == nil {
+		if err := c.Load(); err != nil {
+			return err
+		}
+	}
+	return c.decode()
 }
 
-// EnsureFresh loads from cache if fresh, otherwise refreshes
+// reload reads the cached data from disk again before it is changed, with
+// the lock held, so that the change is made to what another process may have
+// saved since it was loaded. Without a file, the data in memory is kept.
+func (c *Cache) reload() error {
+	if c.cfg.DataFolder != "" {
+		if _, _, err := c.statDB(); err == nil {
+			c.data, c.raw = nil, nil
+		}
+	}
+	return c.ensureData()
+}
+
+// EnsureFresh loads from cache if fresh, otherwise refreshes. An unreadable
+// cache is refreshed too.
 func (c *Cache) EnsureFresh() error {
+	return c.EnsureMaxAge(c.cfg.RefreshRate)
+}
+
+// EnsureMaxAge is EnsureFresh with a max age other than the refresh rate.
+// Either way the data is decoded, ready for Data.
+func (c *Cache) EnsureMaxAge(maxAge time.Duration) error {
 	if c.cfg.DataFolder == "" {
 		return c.Refresh()
 	}
-	if c.NeedsRefresh() {
-		return c.Refresh()
+	if !c.OlderThan(maxAge) {
+		err := c.Load()
+		if err == nil {
+			err = c.decode()
+		}
+		if err == nil {
+			return nil
+		}
+		utils.Log("could not load cache, refreshing: %v", err)
+	}
+	if err := c.Refresh(); err != nil {
+		return err
 	}
-	return c.Load()
+	// Refresh may have used another process's snapshot, loaded but not decoded
+	return c.ensureData()
 }
 
-// Data returns the cached data
+// Data returns the cached data, decoding it first if Load left it encoded.
+// It is nil if nothing was loaded, or if the data doesn't decode (then the
+// file is moved aside, see decode); EnsureFresh reports that as an error.
 func (c *Cache) Data() *CachedData {
+	if err := c.decode(); err != nil {
+		utils.Log("warning: %v", err)
+	}
 	return c.data
 }
 
+// Task looks up a cached task by ID, loading the cache from disk if needed
+func (c *Cache) Task(id string) (todoist.Task, bool) {
+	if err := c.ensureData(); err != nil {
+		return todoist.Task{}, false
+	}
+	return c.data.Index().Task(id)
+}
+
+// PutTask inserts a task returned by the API (or replaces the cached copy)
+// and saves, so a new task shows up in queries without a full refresh
+func (c *Cache) PutTask(t todoist.Task) error {
+	return c.withLock(func() error {
+		if err := c.reload(); err != nil {
+			return err
+		}
+		c.data.Tasks = mergeByID(c.data.Tasks, []todoist.Task{t},
+			func(t todoist.Task) string { return t.ID },
+			func(t todoist.Task) bool { return t.Checked || t.IsDeleted })
+		c.data.invalidateIndex()
+
+		if err := c.save(); err != nil {
+			return err
+		}
+		c.saveCounts()
+		return nil
+	})
+}
+
 // LoadLabelCounts reads label counts from disk
 func (c *Cache) LoadLabelCounts() (map[string]int, error) {
 	return loadJSONMap(c.labelCountsPath())
@@ -150,18 +395,56 @@ func (c *Cache) save() error {
 	if c.cfg.DataFolder == "" {
 		return nil
 	}
+	if c.cfg.CacheFormat == config.CacheFormatGob {
+		return writeAtomic(c.dbPath(), func(w io.Writer) error { return encodeGob(w, c.data) })
+	}
 	return saveJSON(c.dbPath(), c.data)
 }
 
+// saveCounts writes label and project counts derived from the cached data
+func (c *Cache) saveCounts() {
+	labelCounts := ComputeLabelCounts(c.data.Tasks, c.data.Labels)
+	if err := saveJSON(c.labelCountsPath(), labelCounts); err != nil {
+		utils.Log("warning: failed to save label counts: %v", err)
+	}
+
+	projectCounts := ComputeProjectCounts(c.data.Tasks, c.data.Projects, c.data.Sections)
+	if err := saveJSON(c.projectCountsPath(), projectCounts); err != nil {
+		utils.Log("warning: failed to save project counts: %v", err)
+	}
+}
+
+// saveJSON writes v to path as indented JSON
 func saveJSON(path string, v any) error {
-	f, err := os.Create(path)
+	return writeAtomic(path, func(w io.Writer) error { return encodeJSON(w, v) })
+}
+
+// writeAtomic writes to a temporary file next to path and renames it into
+// place, so readers see either the old or the new file but never a
+// partially written one
+func writeAtomic(path string, write func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "    ")
-	return enc.Encode(v)
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op once renamed
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
 }
 
 func loadJSONMap(path string) (map[string]int, error) {
@@ -3,8 +3,13 @@ package cache
 import (
 	"alfredo-go/pkg/config"
 	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/todoist/todoisttest"
+	"encoding/json"
+	"errors"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -12,7 +17,7 @@ import (
 func TestNeedsRefresh_MissingFile(t *testing.T) {
 	cfg := &config.Config{
 		DataFolder:  t.TempDir(),
-		RefreshRate: 1,
+		RefreshRate: 24 * time.Hour,
 	}
 	c := NewCache(nil, cfg)
 	if !c.NeedsRefresh() {
@@ -24,7 +29,7 @@ func TestNeedsRefresh_FreshFile(t *testing.T) {
 	dir := t.TempDir()
 	cfg := &config.Config{
 		DataFolder:  dir,
-		RefreshRate: 1,
+		RefreshRate: 24 * time.Hour,
 	}
 
 	// Create a fresh file
@@ -41,7 +46,7 @@ func TestNeedsRefresh_StaleFile(t *testing.T) {
 	dir := t.TempDir()
 	cfg := &config.Config{
 		DataFolder:  dir,
-		RefreshRate: 0, // 0 days = always refresh
+		RefreshRate: 0, // always refresh
 	}
 
 	// Create a file and backdate it
@@ -59,7 +64,7 @@ func TestNeedsRefresh_StaleFile(t *testing.T) {
 func TestNeedsRefresh_EmptyDataFolder(t *testing.T) {
 	cfg := &config.Config{
 		DataFolder:  "",
-		RefreshRate: 1,
+		RefreshRate: 24 * time.Hour,
 	}
 	c := NewCache(nil, cfg)
 	if !c.NeedsRefresh() {
@@ -71,7 +76,7 @@ func TestSaveAndLoad(t *testing.T) {
 	dir := t.TempDir()
 	cfg := &config.Config{
 		DataFolder:  dir,
-		RefreshRate: 1,
+		RefreshRate: 24 * time.Hour,
 	}
 
 	c := NewCache(nil, cfg)
@@ -150,3 +155,589 @@ func TestComputeProjectCounts(t *testing.T) {
 		t.Errorf("Work/Urgent count = %d, want 1", counts["Work/Urgent"])
 	}
 }
+
+func TestApplySync_Incremental(t *testing.T) {
+	data := &CachedData{
+		Tasks: []todoist.Task{
+			{ID: "1", Content: "Keep me"},
+			{ID: "2", Content: "Old content"},
+			{ID: "3", Content: "Complete me"},
+			{ID: "4", Content: "Delete me"},
+		},
+		Projects:  []todoist.Project{{ID: "p1", Name: "Inbox"}, {ID: "p2", Name: "Gone"}},
+		Labels:    []todoist.Label{{ID: "l1", Name: "work"}},
+		Filters:   []todoist.Filter{{ID: "f1", Name: "Old", Query: "today"}},
+		SyncToken: "old",
+	}
+
+	data.applySync(&todoist.SyncAllResponse{
+		SyncToken: "new",
+		Items: []todoist.Task{
+			{ID: "2", Content: "New content"},
+			{ID: "3", Checked: true},
+			{ID: "4", IsDeleted: true},
+			{ID: "5", Content: "Added"},
+		},
+		Projects: []todoist.Project{{ID: "p2", IsDeleted: true}},
+		Labels:   []todoist.Label{{ID: "l2", Name: "home"}},
+		Filters:  []todoist.Filter{{ID: "f1", IsDeleted: true}, {ID: "f2", Name: "New", Query: "p1"}},
+	})
+
+	if data.SyncToken != "new" {
+		t.Errorf("SyncToken = %q, want %q", data.SyncToken, "new")
+	}
+	var contents []string
+	for _, task := range data.Tasks {
+		contents = append(contents, task.Content)
+	}
+	want := []string{"Keep me", "New content", "Added"}
+	if len(contents) != len(want) {
+		t.Fatalf("tasks = %v, want %v", contents, want)
+	}
+	for i := range want {
+		if contents[i] != want[i] {
+			t.Errorf("tasks[%d] = %q, want %q", i, contents[i], want[i])
+		}
+	}
+	if len(data.Projects) != 1 || data.Projects[0].ID != "p1" {
+		t.Errorf("projects = %v, want only p1", data.Projects)
+	}
+	if len(data.Labels) != 2 {
+		t.Errorf("expected 2 labels, got %d", len(data.Labels))
+	}
+	if len(data.Filters) != 1 || data.Filters[0].ID != "f2" {
+		t.Errorf("filters = %v, want only f2", data.Filters)
+	}
+}
+
+func TestApplySync_FullReplaces(t *testing.T) {
+	data := &CachedData{
+		Tasks:     []todoist.Task{{ID: "1", Content: "Stale"}},
+		SyncToken: "old",
+	}
+
+	data.applySync(&todoist.SyncAllResponse{
+		SyncToken: "fresh",
+		FullSync:  true,
+		Items:     []todoist.Task{{ID: "2", Content: "Fresh"}},
+	})
+
+	if len(data.Tasks) != 1 || data.Tasks[0].ID != "2" {
+		t.Errorf("tasks = %v, want only task 2", data.Tasks)
+	}
+	if data.SyncToken != "fresh" {
+		t.Errorf("SyncToken = %q, want %q", data.SyncToken, "fresh")
+	}
+}
+
+func TestEnqueueAppliesLocally(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{DataFolder: dir, RefreshRate: 24 * time.Hour}
+
+	c := NewCache(nil, cfg)
+	c.data = &CachedData{
+		Tasks: []todoist.Task{
+			{ID: "1", Content: "Done soon"},
+			{ID: "2", Content: "Move me", ProjectID: "p1"},
+		},
+	}
+	if err := c.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+
+	cmds := []todoist.Command{
+		todoist.NewCommand("item_close", map[string]any{"id": "1"}),
+		todoist.NewCommand("item_update", map[string]any{"id": "2", "priority": 4, "due": map[string]string{"date": "2025-03-15"}}),
+		{Type: "item_add", UUID: "u3", TempID: "tmp", Args: map[string]any{"content": "Offline task", "labels": []string{"work"}}},
+	}
+	for _, cmd := range cmds {
+		if err := c.Enqueue(cmd); err != nil {
+			t.Fatalf("Enqueue(%s) error: %v", cmd.Type, err)
+		}
+	}
+
+	queued, err := c.LoadQueue()
+	if err != nil {
+		t.Fatalf("LoadQueue() error: %v", err)
+	}
+	if len(queued) != 3 || queued[0].UUID != cmds[0].UUID || queued[2].TempID != "tmp" {
+		t.Errorf("queue = %+v, want the 3 commands in order", queued)
+	}
+
+	// A fresh cache must see the optimistic changes on disk
+	c2 := NewCache(nil, cfg)
+	if err := c2.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	tasks := c2.Data().Tasks
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(tasks))
+	}
+	if tasks[0].ID != "2" || tasks[0].Priority != 4 || tasks[0].Due == nil || tasks[0].Due.Date != "2025-03-15" {
+		t.Errorf("updated task = %+v", tasks[0])
+	}
+	if tasks[1].ID != "tmp" || tasks[1].Content != "Offline task" || len(tasks[1].Labels) != 1 {
+		t.Errorf("added task = %+v", tasks[1])
+	}
+
+	if err := c.SaveQueue(nil); err != nil {
+		t.Fatalf("SaveQueue(nil) error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "queue.json")); !os.IsNotExist(err) {
+		t.Error("queue file should be removed once empty")
+	}
+}
+
+func TestReplayQueue(t *testing.T) {
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	c := NewCache(nil, cfg)
+	c.data = &CachedData{}
+	c.save()
+	sent := []todoist.Command{todoist.ItemClose("1"), todoist.ItemClose("2")}
+	if err := c.SaveQueue(sent); err != nil {
+		t.Fatalf("SaveQueue() error: %v", err)
+	}
+
+	late := todoist.ItemClose("3")
+	enqueued := make(chan error)
+	pending, _, err := c.ReplayQueue(func(cmds []todoist.Command) (*todoist.CommandsResponse, error) {
+		// Queued by another process while the commands are on their way
+		go func() { enqueued <- NewCache(nil, cfg).Enqueue(late) }()
+		time.Sleep(50 * time.Millisecond)
+		// The request for the second command failed
+		return &todoist.CommandsResponse{SyncStatus: map[string]json.RawMessage{
+			cmds[0].UUID: json.RawMessage(`"ok"`),
+		}}, errors.New("connection reset")
+	})
+	if err == nil || len(pending) != 2 {
+		t.Errorf("ReplayQueue = %d pending, %v; want 2 and the send error", len(pending), err)
+	}
+	if err := <-enqueued; err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	queued, _ := c.LoadQueue()
+	if len(queued) != 2 || queued[0].UUID != sent[1].UUID || queued[1].UUID != late.UUID {
+		t.Errorf("queue = %+v, want the unsent command and the late one", queued)
+	}
+}
+
+func TestPushUndoKeepsLatestEntries(t *testing.T) {
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	c := NewCache(nil, cfg)
+
+	for i := 0; i < maxUndoEntries+5; i++ {
+		entry := UndoEntry{Action: "complete", Tasks: []todoist.Task{{ID: string(rune('a' + i))}}}
+		if err := c.PushUndo(entry); err != nil {
+			t.Fatalf("PushUndo() error: %v", err)
+		}
+	}
+
+	entries, err := c.LoadUndo()
+	if err != nil {
+		t.Fatalf("LoadUndo() error: %v", err)
+	}
+	if len(entries) != maxUndoEntries {
+		t.Fatalf("expected %d entries, got %d", maxUndoEntries, len(entries))
+	}
+	if last := entries[len(entries)-1].Tasks[0].ID; last != string(rune('a'+maxUndoEntries+4)) {
+		t.Errorf("last entry task = %q, want the most recent one", last)
+	}
+}
+
+func TestRemoveUndoKeepsNewerEntries(t *testing.T) {
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	c := NewCache(nil, cfg)
+	first := UndoEntry{Action: "complete", At: time.Now().Add(-time.Minute)}
+	c.PushUndo(first)
+	// Pushed by another process while first was being undone
+	c.PushUndo(UndoEntry{Action: "delete", At: time.Now()})
+
+	if err := c.RemoveUndo(first); err != nil {
+		t.Fatalf("RemoveUndo() error: %v", err)
+	}
+	entries, _ := c.LoadUndo()
+	if len(entries) != 1 || entries[0].Action != "delete" {
+		t.Errorf("entries = %+v, want only the delete", entries)
+	}
+}
+
+func TestChangesKeepOtherProcessesWrites(t *testing.T) {
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	c1 := NewCache(nil, cfg)
+	c1.data = &CachedData{Tasks: []todoist.Task{{ID: "1", Content: "existing"}}}
+	if err := c1.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+	c2 := NewCache(nil, cfg)
+	if err := c2.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	// Both loaded the same snapshot; neither change may undo the other
+	if err := c1.PutTask(todoist.Task{ID: "2", Content: "from c1"}); err != nil {
+		t.Fatalf("PutTask: %v", err)
+	}
+	if err := c2.Enqueue(todoist.NewCommand("item_update", map[string]any{"id": "1", "priority": 4})); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	c := NewCache(nil, cfg)
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	tasks := c.Data().Tasks
+	if len(tasks) != 2 || tasks[0].Priority != 4 || tasks[1].ID != "2" {
+		t.Errorf("tasks = %+v, want both changes", tasks)
+	}
+}
+
+func TestChangesWaitForTheLock(t *testing.T) {
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	c := NewCache(nil, cfg)
+	c.data = &CachedData{}
+	if err := c.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+
+	// Another process is refreshing
+	lock, err := acquireLock(c.lockPath(), false)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- c.PutTask(todoist.Task{ID: "1"}) }()
+	select {
+	case err := <-done:
+		t.Fatalf("PutTask returned (%v) while another process holds the lock", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	lock.Unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("PutTask: %v", err)
+	}
+}
+
+func TestRefresh_IncrementalAgainstServer(t *testing.T) {
+	srv := todoisttest.NewServer("token")
+	defer srv.Close()
+	srv.AddProject(todoist.Project{ID: "p1", Name: "Work"})
+	srv.AddTask(todoist.Task{ID: "1", Content: "keep", ProjectID: "p1"})
+	srv.AddTask(todoist.Task{ID: "2", Content: "finish", ProjectID: "p1"})
+
+	client := todoist.NewClient("token", srv.URL)
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	if err := NewCache(client, cfg).Refresh(); err != nil {
+		t.Fatalf("first Refresh: %v", err)
+	}
+
+	if err := client.CompleteTask("2"); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddTask(todoist.Task{ID: "3", Content: "new", ProjectID: "p1"})
+
+	// A new Cache picks up the sync token saved by the first one
+	c := NewCache(client, cfg)
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("second Refresh: %v", err)
+	}
+
+	tokens := srv.SyncTokens()
+	if len(tokens) != 2 || tokens[0] != "*" || tokens[1] == "*" {
+		t.Errorf("sync tokens sent = %v, want a full then an incremental sync", tokens)
+	}
+	var ids []string
+	for _, task := range c.Data().Tasks {
+		ids = append(ids, task.ID)
+	}
+	if len(ids) != 2 || ids[0] != "1" || ids[1] != "3" {
+		t.Errorf("cached tasks = %v, want [1 3]", ids)
+	}
+	if counts, _ := c.LoadProjectCounts(); counts["Work"] != 2 {
+		t.Errorf("project counts = %v, want Work: 2", counts)
+	}
+}
+
+func TestRefresh_FullSyncOnlyWhenTokenRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantErr  bool
+		wantFull bool
+	}{
+		{"token expired", http.StatusGone, false, true},
+		{"bad token", http.StatusBadRequest, false, true},
+		{"rate limited", http.StatusTooManyRequests, true, false},
+		{"server down", http.StatusServiceUnavailable, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := todoisttest.NewServer("token")
+			defer srv.Close()
+			srv.AddTask(todoist.Task{ID: "1", Content: "keep"})
+
+			client := todoist.NewClient("token", srv.URL)
+			client.SetRetries(0, 0)
+			cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+			if err := NewCache(client, cfg).Refresh(); err != nil {
+				t.Fatalf("first Refresh: %v", err)
+			}
+
+			srv.FailNext(tt.status)
+			err := NewCache(client, cfg).Refresh()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Refresh error = %v, want error: %v", err, tt.wantErr)
+			}
+			tokens := srv.SyncTokens()
+			full := len(tokens) == 2 && tokens[1] == "*"
+			if full != tt.wantFull {
+				t.Errorf("sync tokens sent = %v, want a full sync: %v", tokens, tt.wantFull)
+			}
+		})
+	}
+}
+
+func TestRefresh_KeepsOtherProcessesWrites(t *testing.T) {
+	srv := todoisttest.NewServer("token")
+	defer srv.Close()
+	srv.AddTask(todoist.Task{ID: "1", Content: "keep"})
+
+	client := todoist.NewClient("token", srv.URL)
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	c := NewCache(client, cfg)
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("first Refresh: %v", err)
+	}
+
+	// Another process adds a task after c loaded the cache
+	if err := NewCache(client, cfg).PutTask(todoist.Task{ID: "2", Content: "created elsewhere"}); err != nil {
+		t.Fatalf("PutTask: %v", err)
+	}
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("second Refresh: %v", err)
+	}
+
+	loaded := NewCache(client, cfg)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, ok := loaded.Task("2"); !ok {
+		t.Error("refresh dropped the task saved by the other process")
+	}
+}
+
+func TestResetSyncTokenForcesFullSync(t *testing.T) {
+	srv := todoisttest.NewServer("token")
+	defer srv.Close()
+	srv.AddTask(todoist.Task{ID: "1", Content: "keep"})
+
+	client := todoist.NewClient("token", srv.URL)
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	c := NewCache(client, cfg)
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("first Refresh: %v", err)
+	}
+
+	// The token saved on disk is ignored, not reloaded
+	c.ResetSyncToken()
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("second Refresh: %v", err)
+	}
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("third Refresh: %v", err)
+	}
+	tokens := srv.SyncTokens()
+	if len(tokens) != 3 || tokens[1] != "*" || tokens[2] == "*" {
+		t.Errorf("sync tokens sent = %v, want two full syncs, then an incremental one", tokens)
+	}
+}
+
+func TestSaveJSONLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "labelCounts.json")
+	if err := saveJSON(path, map[string]int{"work": 1}); err != nil {
+		t.Fatalf("saveJSON: %v", err)
+	}
+	if err := saveJSON(path, map[string]int{"work": 2}); err != nil {
+		t.Fatalf("saveJSON: %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("data folder holds %d files, want only the saved one", len(entries))
+	}
+	if m, err := loadJSONMap(path); err != nil || m["work"] != 2 {
+		t.Errorf("loadJSONMap = %v, %v; want work: 2", m, err)
+	}
+}
+
+func TestEnsureFresh_RecoversFromCorruptCache(t *testing.T) {
+	srv := todoisttest.NewServer("token")
+	defer srv.Close()
+	srv.AddTask(todoist.Task{ID: "1", Content: "keep"})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "allData.json")
+	os.WriteFile(path, []byte(`{"tasks": [{"id": "1", "cont`), 0644)
+
+	cfg := &config.Config{DataFolder: dir, RefreshRate: 24 * time.Hour}
+	c := NewCache(todoist.NewClient("token", srv.URL), cfg)
+	if err := c.EnsureFresh(); err != nil {
+		t.Fatalf("EnsureFresh: %v", err)
+	}
+
+	if tokens := srv.SyncTokens(); len(tokens) != 1 || tokens[0] != "*" {
+		t.Errorf("sync tokens sent = %v, want a single full sync", tokens)
+	}
+	if len(c.Data().Tasks) != 1 {
+		t.Errorf("expected 1 task after recovery, got %d", len(c.Data().Tasks))
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("corrupt cache should be kept aside: %v", err)
+	}
+	if err := NewCache(nil, cfg).Load(); err != nil {
+		t.Errorf("Load after recovery: %v", err)
+	}
+}
+
+func TestRefresh_UsesSnapshotWhileLocked(t *testing.T) {
+	srv := todoisttest.NewServer("token")
+	defer srv.Close()
+	srv.AddTask(todoist.Task{ID: "1", Content: "keep"})
+
+	client := todoist.NewClient("token", srv.URL)
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	if err := NewCache(client, cfg).Refresh(); err != nil {
+		t.Fatalf("first Refresh: %v", err)
+	}
+	srv.AddTask(todoist.Task{ID: "2", Content: "new"})
+
+	// Another process is in the middle of a refresh
+	c := NewCache(client, cfg)
+	lock, err := acquireLock(c.lockPath(), false)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("Refresh while locked: %v", err)
+	}
+	if n := len(srv.SyncTokens()); n != 1 {
+		t.Errorf("%d syncs, want none while another process holds the lock", n-1)
+	}
+	if len(c.Data().Tasks) != 1 {
+		t.Errorf("expected the snapshot's 1 task, got %d", len(c.Data().Tasks))
+	}
+
+	lock.Unlock()
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("Refresh after unlock: %v", err)
+	}
+	if len(c.Data().Tasks) != 2 {
+		t.Errorf("expected 2 tasks once unlocked, got %d", len(c.Data().Tasks))
+	}
+}
+
+func TestRefresh_ConcurrentProcesses(t *testing.T) {
+	srv := todoisttest.NewServer("token")
+	defer srv.Close()
+	for i := 0; i < 50; i++ {
+		srv.AddTask(todoist.Task{ID: string(rune('a' + i)), Content: "task"})
+	}
+
+	client := todoist.NewClient("token", srv.URL)
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- NewCache(client, cfg).Refresh() }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Refresh: %v", err)
+		}
+	}
+
+	c := NewCache(nil, cfg)
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load after concurrent refreshes: %v", err)
+	}
+	if len(c.Data().Tasks) != 50 {
+		t.Errorf("expected 50 tasks, got %d", len(c.Data().Tasks))
+	}
+}
+
+func TestRefreshRunning(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCache(nil, &config.Config{DataFolder: dir, RefreshRate: 24 * time.Hour})
+	pidfile := filepath.Join(dir, "refresh.pid")
+
+	if c.RefreshRunning() {
+		t.Error("no refresh should be running without a pidfile")
+	}
+
+	// Claimed but not started yet
+	os.WriteFile(pidfile, nil, 0644)
+	if !c.RefreshRunning() {
+		t.Error("a freshly claimed pidfile should count as running")
+	}
+	if started, err := c.StartBackgroundRefresh("refresh"); started || err != nil {
+		t.Errorf("StartBackgroundRefresh = %v, %v; want nothing started", started, err)
+	}
+
+	os.WriteFile(pidfile, []byte(strconv.Itoa(os.Getpid())), 0644)
+	if !c.RefreshRunning() {
+		t.Error("a pidfile with a live process should count as running")
+	}
+	old := time.Now().Add(-maxBackgroundAge - time.Minute)
+	os.Chtimes(pidfile, old, old)
+	if c.RefreshRunning() {
+		t.Error("a pidfile older than maxBackgroundAge should be ignored")
+	}
+
+	os.WriteFile(pidfile, []byte(strconv.Itoa(os.Getpid())), 0644)
+	c.EndBackgroundRefresh()
+	if _, err := os.Stat(pidfile); !os.IsNotExist(err) {
+		t.Error("EndBackgroundRefresh should remove its own pidfile")
+	}
+
+	// A refresh that ends before the process starting it wrote its PID
+	lock, err := acquireLock(c.pidLockPath(), false)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	os.WriteFile(pidfile, nil, 0644)
+	ended := make(chan struct{})
+	go func() { c.EndBackgroundRefresh(); close(ended) }()
+	time.Sleep(50 * time.Millisecond)
+	os.WriteFile(pidfile, []byte(strconv.Itoa(os.Getpid())), 0644)
+	lock.Unlock()
+	<-ended
+	if _, err := os.Stat(pidfile); !os.IsNotExist(err) {
+		t.Error("EndBackgroundRefresh should wait for its PID and remove the pidfile")
+	}
+}
+
+func TestOlderThanAndInvalidate(t *testing.T) {
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour}
+	c := NewCache(nil, cfg)
+	if err := c.Invalidate(); err != nil {
+		t.Errorf("Invalidate without a cache: %v", err)
+	}
+
+	c.data = &CachedData{FetchedAt: time.Now()}
+	if err := c.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+	if c.OlderThan(15 * time.Minute) {
+		t.Error("a cache saved just now is not older than 15 minutes")
+	}
+	if !c.OlderThan(0) {
+		t.Error("a max age of 0 should always need a refresh")
+	}
+
+	if err := c.Invalidate(); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	if !c.NeedsRefresh() || !c.OlderThan(30*24*time.Hour) {
+		t.Error("an invalidated cache should need a refresh")
+	}
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"alfredo-go/pkg/config"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// A gob cache file starts with a fixed header:
+//
+//	magic   [8]byte "ALFREDO\x00"
+//	version uint16  gobVersion
+//	length  uint64  length of the payload
+//	crc     uint32  CRC-32 (IEEE) of the payload
+//
+// followed by the gob-encoded CachedData. The version changes whenever the
+// payload does in a way older binaries can't read; a file with any other
+// version is ignored and replaced by the next refresh.
+const (
+	gobMagic      = "ALFREDO\x00"
+	gobVersion    = 1
+	gobHeaderSize = len(gobMagic) + 2 + 8 + 4
+)
+
+var errUnsupportedVersion = errors.New("unsupported cache format version")
+
+// dbFile is the name of the cached data file in a format
+func dbFile(format string) string {
+	if format == config.CacheFormatGob {
+		return "allData.gob"
+	}
+	return "allData.json"
+}
+
+func (c *Cache) dbPathFor(format string) string {
+	return filepath.Join(c.cfg.DataFolder, dbFile(format))
+}
+
+// otherFormat is the format the cache is not configured to use, whose file
+// may be left over from before the setting changed
+func (c *Cache) otherFormat() string {
+	if c.cfg.CacheFormat == config.CacheFormatGob {
+		return config.CacheFormatJSON
+	}
+	return config.CacheFormatGob
+}
+
+// statDB returns the info of the cached data file, or of the other format's
+// file if only that one exists yet (Load migrates it)
+func (c *Cache) statDB() (string, os.FileInfo, error) {
+	path := c.dbPath()
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if old, oerr := os.Stat(c.dbPathFor(c.otherFormat())); oerr == nil {
+			return c.dbPathFor(c.otherFormat()), old, nil
+		}
+	}
+	return path, info, err
+}
+
+// encodeGob writes data with the gob header
+func encodeGob(w io.Writer, data *CachedData) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(data); err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, gobHeaderSize)
+	header = append(header, gobMagic...)
+	header = binary.BigEndian.AppendUint16(header, gobVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(payload.Len()))
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(payload.Bytes()))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload.Bytes())
+	return err
+}
+
+// readGob checks the header of a gob cache file and returns its payload,
+// still encoded. errUnsupportedVersion means the file is fine but written
+// by another version; any other error means it is damaged.
+func readGob(r io.Reader) ([]byte, error) {
+	header := make([]byte, gobHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(header[:len(gobMagic)]) != gobMagic {
+		return nil, errors.New("not a cache file")
+	}
+	rest := header[len(gobMagic):]
+	if v := binary.BigEndian.Uint16(rest); v != gobVersion {
+		return nil, fmt.Errorf("%w %d", errUnsupportedVersion, v)
+	}
+	length := binary.BigEndian.Uint64(rest[2:])
+	crc := binary.BigEndian.Uint32(rest[10:])
+
+	var payload bytes.Buffer
+	if n, err := io.Copy(&payload, io.LimitReader(r, int64(length))); err != nil {
+		return nil, err
+	} else if uint64(n) != length {
+		return nil, fmt.Errorf("truncated: %d of %d bytes", n, length)
+	}
+	if crc32.ChecksumIEEE(payload.Bytes()) != crc {
+		return nil, errors.New("checksum mismatch")
+	}
+	return payload.Bytes(), nil
+}
+
+// decodeGob decodes a payload returned by readGob
+func decodeGob(payload []byte) (*CachedData, error) {
+	data := &CachedData{}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// encodeJSON writes data as indented JSON, readable by hand
+func encodeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(v)
+}
+
+// decodeFormat reads cached data in a format
+func decodeFormat(r io.Reader, format string) (*CachedData, error) {
+	if format == config.CacheFormatGob {
+		payload, err := readGob(bufio.NewReader(r))
+		if err != nil {
+			return nil, err
+		}
+		return decodeGob(payload)
+	}
+	data := &CachedData{}
+	if err := json.NewDecoder(r).Decode(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"alfredo-go/pkg/config"
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/todoist/todoisttest"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testFormatData() *CachedData {
+	return &CachedData{
+		Tasks:     []todoist.Task{{ID: "1", Content: "Test task", Labels: []string{"work"}, Due: &todoist.Due{Date: "2026-10-18"}}},
+		Projects:  []todoist.Project{{ID: "p1", Name: "Inbox"}},
+		User:      &todoist.UserInfo{ID: "u1"},
+		SyncToken: "token",
+		FetchedAt: time.Now(),
+	}
+}
+
+func TestGobSaveAndLoad(t *testing.T) {
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour, CacheFormat: config.CacheFormatGob}
+	c := NewCache(nil, cfg)
+	c.data = testFormatData()
+	if err := c.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.DataFolder, "allData.gob")); err != nil {
+		t.Fatalf("gob cache not written: %v", err)
+	}
+
+	c2 := NewCache(nil, cfg)
+	if err := c2.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	data := c2.Data()
+	if len(data.Tasks) != 1 || data.Tasks[0].Content != "Test task" || data.Tasks[0].Due.Date != "2026-10-18" {
+		t.Errorf("tasks = %+v", data.Tasks)
+	}
+	if data.User == nil || data.User.ID != "u1" || data.SyncToken != "token" {
+		t.Errorf("user = %+v, sync token = %q", data.User, data.SyncToken)
+	}
+	if !data.FetchedAt.Equal(c.data.FetchedAt) {
+		t.Errorf("fetched at = %v, want %v", data.FetchedAt, c.data.FetchedAt)
+	}
+	if _, ok := c2.Task("1"); !ok {
+		t.Error("Task(1) should be found")
+	}
+}
+
+func TestLoad_MigratesFormats(t *testing.T) {
+	for _, tt := range []struct{ from, to string }{
+		{config.CacheFormatJSON, config.CacheFormatGob},
+		{config.CacheFormatGob, config.CacheFormatJSON},
+	} {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			dir := t.TempDir()
+			old := NewCache(nil, &config.Config{DataFolder: dir, CacheFormat: tt.from})
+			old.data = testFormatData()
+			if err := old.save(); err != nil {
+				t.Fatalf("save() error: %v", err)
+			}
+			oldPath := filepath.Join(dir, dbFile(tt.from))
+			mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+			os.Chtimes(oldPath, mtime, mtime)
+
+			c := NewCache(nil, &config.Config{DataFolder: dir, RefreshRate: 24 * time.Hour, CacheFormat: tt.to})
+			if c.NeedsRefresh() {
+				t.Error("the old file should count as fresh until migrated")
+			}
+			if err := c.Load(); err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if len(c.Data().Tasks) != 1 || c.Data().SyncToken != "token" {
+				t.Errorf("migrated data = %+v", c.Data())
+			}
+
+			if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+				t.Errorf("%s should be removed after migrating: %v", dbFile(tt.from), err)
+			}
+			info, err := os.Stat(filepath.Join(dir, dbFile(tt.to)))
+			if err != nil {
+				t.Fatalf("%s not written: %v", dbFile(tt.to), err)
+			}
+			if !info.ModTime().Equal(mtime) {
+				t.Errorf("migrated cache modified %v, want the old file's %v", info.ModTime(), mtime)
+			}
+			if err := NewCache(nil, c.cfg).Load(); err != nil {
+				t.Errorf("Load after migrating: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoad_DamagedGob(t *testing.T) {
+	tests := []struct {
+		name      string
+		damage    func([]byte) []byte
+		checkOnly bool // Load only checks header and checksum: the damage shows on decoding
+	}{
+		{"truncated", func(b []byte) []byte { return b[:len(b)-10] }, false},
+		{"short header", func(b []byte) []byte { return b[:5] }, false},
+		{"checksum", func(b []byte) []byte { b[len(b)-1] ^= 0xff; return b }, false},
+		{"not a cache", func(b []byte) []byte { return []byte(`{"tasks": []}`) }, false},
+		{"undecodable payload", func(b []byte) []byte {
+			// Intact header and checksum around a payload gob can't decode
+			payload := []byte("not gob at all")
+			b = b[:gobHeaderSize]
+			binary.BigEndian.PutUint64(b[len(gobMagic)+2:], uint64(len(payload)))
+			binary.BigEndian.PutUint32(b[len(gobMagic)+10:], crc32.ChecksumIEEE(payload))
+			return append(b, payload...)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DataFolder: t.TempDir(), CacheFormat: config.CacheFormatGob}
+			c := NewCache(nil, cfg)
+			c.data = testFormatData()
+			if err := c.save(); err != nil {
+				t.Fatalf("save() error: %v", err)
+			}
+			path := c.dbPath()
+			b, _ := os.ReadFile(path)
+			os.WriteFile(path, tt.damage(b), 0644)
+
+			c = NewCache(nil, cfg)
+			err := c.Load()
+			if tt.checkOnly {
+				if err != nil {
+					t.Fatalf("Load should only check the file: %v", err)
+				}
+				if c.Data() != nil {
+					t.Fatal("Data should be nil when the payload doesn't decode")
+				}
+			} else if err == nil {
+				t.Fatal("Load should fail")
+			}
+			if _, err := os.Stat(path + ".corrupt"); err != nil {
+				t.Errorf("damaged cache should be kept aside: %v", err)
+			}
+			// A change without the data is not saved as the whole cache
+			if err := NewCache(nil, cfg).PutTask(todoist.Task{ID: "2"}); err == nil {
+				t.Error("PutTask should fail without a cache to add to")
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("no cache should be written in place of the damaged one: %v", err)
+			}
+		})
+	}
+}
+
+func TestEnsureFresh_GobVersionMismatch(t *testing.T) {
+	srv := todoisttest.NewServer("token")
+	defer srv.Close()
+	srv.AddTask(todoist.Task{ID: "1", Content: "keep"})
+
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour, CacheFormat: config.CacheFormatGob}
+	c := NewCache(todoist.NewClient("token", srv.URL), cfg)
+	c.data = testFormatData()
+	if err := c.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+	// Written by a newer version
+	path := c.dbPath()
+	b, _ := os.ReadFile(path)
+	binary.BigEndian.PutUint16(b[len(gobMagic):], gobVersion+1)
+	os.WriteFile(path, b, 0644)
+
+	c = NewCache(todoist.NewClient("token", srv.URL), cfg)
+	if err := c.EnsureFresh(); err != nil {
+		t.Fatalf("EnsureFresh: %v", err)
+	}
+	if tokens := srv.SyncTokens(); len(tokens) != 1 || tokens[0] != "*" {
+		t.Errorf("sync tokens sent = %v, want a single full sync", tokens)
+	}
+	if _, err := os.Stat(path + ".corrupt"); !os.IsNotExist(err) {
+		t.Errorf("a cache from another version is not corrupt: %v", err)
+	}
+	if err := NewCache(nil, cfg).Load(); err != nil {
+		t.Errorf("Load after refresh: %v", err)
+	}
+}
+
+func TestEnsureFresh_UndecodableGob(t *testing.T) {
+	srv := todoisttest.NewServer("token")
+	defer srv.Close()
+	srv.AddTask(todoist.Task{ID: "1", Content: "keep"})
+
+	cfg := &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour, CacheFormat: config.CacheFormatGob}
+	c := NewCache(todoist.NewClient("token", srv.URL), cfg)
+	c.data = testFormatData()
+	if err := c.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+	// A fresh file whose checksum holds but whose payload doesn't decode
+	path := c.dbPath()
+	b, _ := os.ReadFile(path)
+	payload := []byte("not gob at all")
+	b = b[:gobHeaderSize]
+	binary.BigEndian.PutUint64(b[len(gobMagic)+2:], uint64(len(payload)))
+	binary.BigEndian.PutUint32(b[len(gobMagic)+10:], crc32.ChecksumIEEE(payload))
+	os.WriteFile(path, append(b, payload...), 0644)
+
+	c = NewCache(todoist.NewClient("token", srv.URL), cfg)
+	if err := c.EnsureFresh(); err != nil {
+		t.Fatalf("EnsureFresh: %v", err)
+	}
+	if tokens := srv.SyncTokens(); len(tokens) != 1 || tokens[0] != "*" {
+		t.Errorf("sync tokens sent = %v, want a single full sync", tokens)
+	}
+	if c.Data() == nil {
+		t.Fatal("Data should come from the full sync")
+	}
+	if _, ok := c.Data().Index().Task("1"); !ok {
+		t.Error("task 1 should come from the full sync")
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	f := todoisttest.NewFixture(10000, time.Now())
+	data := &CachedData{Tasks: f.Tasks, Projects: f.Projects, Sections: f.Sections,
+		Labels: f.Labels, Notes: f.Notes, User: &todoist.UserInfo{ID: "u1"}, FetchedAt: time.Now()}
+
+	for _, format := range []string{config.CacheFormatJSON, config.CacheFormatGob} {
+		cfg := &config.Config{DataFolder: b.TempDir(), CacheFormat: format}
+		c := NewCache(nil, cfg)
+		c.data = data
+		if err := c.save(); err != nil {
+			b.Fatalf("save() error: %v", err)
+		}
+		info, _ := os.Stat(c.dbPath())
+
+		b.Run(format, func(b *testing.B) {
+			b.ReportMetric(float64(info.Size()), "file-bytes")
+			for range b.N {
+				c := NewCache(nil, cfg)
+				if err := c.Load(); err != nil {
+					b.Fatal(err)
+				}
+				if c.Data() == nil {
+					b.Fatal("no data")
+				}
+			}
+		})
+		if format != config.CacheFormatGob {
+			continue
+		}
+		// What a run that never looks at the data pays, such as one that
+		// only starts a background refresh
+		b.Run(format+"/check only", func(b *testing.B) {
+			b.ReportMetric(float64(info.Size()), "file-bytes")
+			for range b.N {
+				if err := NewCache(nil, cfg).Load(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/utils"
+	"strings"
+)
+
+// Index maps the IDs and names in CachedData to what they identify, and the
+// words of task contents and descriptions to the tasks using them, so that
+// queries don't scan the data for every task they list
+type Index struct {
+	data *CachedData
+
+	tasks      map[string]int    // task ID -> position in Tasks
+	projects   map[string]int    // project ID -> position in Projects
+	projectIDs map[string]string // normalized project name -> ID
+	sections   map[string]int    // section ID -> position in Sections
+	sectionIDs map[string]string // project ID + "/" + normalized section name -> ID
+	labels     map[string]bool   // normalized label names
+	subtasks   map[string]int    // task ID -> number of open subtasks
+	comments   map[string]int    // task ID -> number of comments
+	words      map[string][]int  // folded word -> positions in Tasks, built by the first Search
+}
+
+// Index returns the index of the data, building it on first use after the
+// data was loaded or changed
+func (d *CachedData) Index() *Index {
+	if d.index == nil {
+		d.index = buildIndex(d)
+	}
+	return d.index
+}
+
+// invalidateIndex drops the index after the data changed
+func (d *CachedData) invalidateIndex() {
+	d.index = nil
+}
+
+func buildIndex(d *CachedData) *Index {
+	idx := &Index{
+		data:       d,
+		tasks:      make(map[string]int, len(d.Tasks)),
+		projects:   make(map[string]int, len(d.Projects)),
+		projectIDs: make(map[string]string, len(d.Projects)),
+		sections:   make(map[string]int, len(d.Sections)),
+		sectionIDs: make(map[string]string, len(d.Sections)),
+		labels:     make(map[string]bool, len(d.Labels)),
+		subtasks:   map[string]int{},
+		comments:   map[string]int{},
+	}
+
+	for i, p := range d.Projects {
+		idx.projects[p.ID] = i
+		// Like a scan would, the first project with a name wins
+		name := utils.NormalizeUnicode(p.Name)
+		if _, ok := idx.projectIDs[name]; !ok {
+			idx.projectIDs[name] = p.ID
+		}
+	}
+	for i, s := range d.Sections {
+		idx.sections[s.ID] = i
+		key := s.ProjectID + "/" + utils.NormalizeUnicode(s.Name)
+		if _, ok := idx.sectionIDs[key]; !ok {
+			idx.sectionIDs[key] = s.ID
+		}
+	}
+	for _, l := range d.Labels {
+		if !l.IsDeleted {
+			idx.labels[utils.NormalizeUnicode(l.Name)] = true
+		}
+	}
+	for _, n := range d.Notes {
+		idx.comments[n.ItemID]++
+	}
+
+	for i, t := range d.Tasks {
+		idx.tasks[t.ID] = i
+		if t.ParentID != "" {
+			idx.subtasks[t.ParentID]++
+		}
+	}
+	return idx
+}
+
+// indexWords builds the inverted index of task contents and descriptions.
+// Folding every text costs more than everything else put together, so
+// queries without search words skip it.
+func (idx *Index) indexWords() {
+	idx.words = map[string][]int{}
+	seen := map[string]bool{}
+	for i, t := range idx.data.Tasks {
+		clear(seen)
+		for _, w := range utils.Words(utils.Fold(t.Content + " " + t.Description)) {
+			if !seen[w] {
+				seen[w] = true
+				idx.words[w] = append(idx.words[w], i)
+			}
+		}
+	}
+}
+
+// Task looks up a task by ID
+func (idx *Index) Task(id string) (todoist.Task, bool) {
+	i, ok := idx.tasks[id]
+	if !ok {
+		return todoist.Task{}, false
+	}
+	return idx.data.Tasks[i], true
+}
+
+// TaskContent returns the content of the task with the given ID, or "" if unknown
+func (idx *Index) TaskContent(id string) string {
+	t, _ := idx.Task(id)
+	return t.Content
+}
+
+// ProjectName returns the name of a project, or "" if unknown
+func (idx *Index) ProjectName(id string) string {
+	if i, ok := idx.projects[id]; ok {
+		return idx.data.Projects[i].Name
+	}
+	return ""
+}
+
+// ProjectID returns the ID of the project with the given name, or "" if
+// there is none. Names are compared in Unicode normal form.
+func (idx *Index) ProjectID(name string) string {
+	return idx.projectIDs[utils.NormalizeUnicode(name)]
+}
+
+// SectionName returns the name of a section, or "" if unknown
+func (idx *Index) SectionName(id string) string {
+	if i, ok := idx.sections[id]; ok {
+		return idx.data.Sections[i].Name
+	}
+	return ""
+}
+
+// SectionID returns the ID of a section given as "#Project/Section" (the #
+// is optional), or "" if there is none
+func (idx *Index) SectionID(path string) string {
+	projName, sectName, ok := strings.Cut(strings.TrimPrefix(path, "#"), "/")
+	if !ok {
+		return ""
+	}
+	projID := idx.ProjectID(projName)
+	return idx.sectionIDs[projID+"/"+utils.NormalizeUnicode(sectName)]
+}
+
+// HasLabel reports whether a label with this name exists
+func (idx *Index) HasLabel(name string) bool {
+	return idx.labels[utils.NormalizeUnicode(name)]
+}
+
+// Subtasks returns the number of open subtasks of a task
+func (idx *Index) Subtasks(id string) int {
+	return idx.subtasks[id]
+}
+
+// Comments returns the number of comments on a task
+func (idx *Index) Comments(id string) int {
+	return idx.comments[id]
+}
+
+// Search returns the IDs of the tasks whose content or description has a
+// fuzzy match for word (see utils.FuzzyScore), looking at each distinct word
+// once rather than at every task. ok is false for words with spaces or
+// punctuation, which the index can't answer.
+func (idx *Index) Search(word string) (ids map[string]bool, ok bool) {
+	q := utils.Fold(word)
+	if words := utils.Words(q); len(words) != 1 || words[0] != q {
+		return nil, false
+	}
+
+	if idx.words == nil {
+		idx.indexWords()
+	}
+	ids = map[string]bool{}
+	for w, positions := range idx.words {
+		if utils.FuzzyWordScore(q, w) == 0 {
+			continue
+		}
+		for _, i := range positions {
+			ids[idx.data.Tasks[i].ID] = true
+		}
+	}
+	return ids, true
+}
@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"alfredo-go/pkg/config"
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/todoist/todoisttest"
+	"alfredo-go/pkg/utils"
+	"testing"
+	"time"
+)
+
+func testIndexData() *CachedData {
+	return &CachedData{
+		Tasks: []todoist.Task{
+			{ID: "1", Content: "Write the quarterly report", ProjectID: "p1", SectionID: "s1"},
+			{ID: "2", Content: "Buy milk", Description: "oat, from the café", ProjectID: "p2"},
+			{ID: "3", Content: "Collect figures", ProjectID: "p1", ParentID: "1"},
+		},
+		Projects: []todoist.Project{
+			{ID: "p1", Name: "Work"},
+			{ID: "p2", Name: "Cafe\u0301"}, // decomposed é
+			{ID: "p3", Name: "Work"},
+		},
+		Sections: []todoist.Section{{ID: "s1", Name: "Urgent", ProjectID: "p1"}},
+		Labels:   []todoist.Label{{ID: "l1", Name: "waiting"}, {ID: "l2", Name: "gone", IsDeleted: true}},
+		Notes:    []todoist.Note{{ItemID: "1"}, {ItemID: "1"}},
+	}
+}
+
+func TestIndexLookups(t *testing.T) {
+	idx := testIndexData().Index()
+
+	if task, ok := idx.Task("2"); !ok || task.Content != "Buy milk" {
+		t.Errorf("Task(2) = %v, %v", task, ok)
+	}
+	if _, ok := idx.Task("missing"); ok {
+		t.Error("Task(missing) should not be found")
+	}
+	if got := idx.TaskContent(""); got != "" {
+		t.Errorf("TaskContent(\"\") = %q, want none", got)
+	}
+	if got := idx.ProjectName("p2"); got != "Cafe\u0301" {
+		t.Errorf("ProjectName(p2) = %q", got)
+	}
+	if got := idx.ProjectID("Work"); got != "p1" {
+		t.Errorf("ProjectID(Work) = %q, want the first project with the name", got)
+	}
+	if got := idx.ProjectID("Caf\u00e9"); got != "p2" {
+		t.Errorf("ProjectID(Café) = %q, want p2 whatever the normal form", got)
+	}
+	if got := idx.SectionID("#Work/Urgent"); got != "s1" {
+		t.Errorf("SectionID(#Work/Urgent) = %q, want s1", got)
+	}
+	if got := idx.SectionID("Home/Urgent"); got != "" {
+		t.Errorf("SectionID(Home/Urgent) = %q, want none", got)
+	}
+	if got := idx.SectionName("s1"); got != "Urgent" {
+		t.Errorf("SectionName(s1) = %q", got)
+	}
+	if !idx.HasLabel("waiting") || idx.HasLabel("gone") {
+		t.Error("HasLabel should know active labels only")
+	}
+	if idx.Subtasks("1") != 1 || idx.Comments("1") != 2 || idx.Comments("2") != 0 {
+		t.Errorf("subtasks = %d, comments = %d", idx.Subtasks("1"), idx.Comments("1"))
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := testIndexData().Index()
+
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"report", []string{"1"}},
+		{"port", []string{"1"}},   // inside a word
+		{"reprot", []string{"1"}}, // typo
+		{"qrtly", []string{"1"}},  // abbreviation
+		{"CAFE", []string{"2"}},   // description, accents folded
+		{"figures", []string{"3"}},
+		{"the", []string{"1", "2"}},
+		{"nothing", nil},
+	}
+	for _, tt := range tests {
+		ids, ok := idx.Search(tt.word)
+		if !ok {
+			t.Errorf("Search(%q) can't tell", tt.word)
+			continue
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.word, ids, tt.want)
+			continue
+		}
+		for _, id := range tt.want {
+			if !ids[id] {
+				t.Errorf("Search(%q) = %v, want %v", tt.word, ids, tt.want)
+			}
+		}
+	}
+
+	for _, word := range []string{"desc:oat", "e-mail", ""} {
+		if _, ok := idx.Search(word); ok {
+			t.Errorf("Search(%q) should leave %q to a scan", word, word)
+		}
+	}
+}
+
+func TestIndexSearchMatchesScan(t *testing.T) {
+	data := todoisttest.NewFixture(2000, time.Now())
+	d := &CachedData{Tasks: data.Tasks}
+	idx := d.Index()
+
+	for _, word := range []string{"rep", "report", "reprot", "mtg", "cafe", "resume", "invoce", "q", "zzz"} {
+		ids, ok := idx.Search(word)
+		if !ok {
+			t.Fatalf("Search(%q) can't tell", word)
+		}
+		for _, task := range d.Tasks {
+			scan := utils.FuzzyScore(word, task.Content) > 0 || utils.FuzzyScore(word, task.Description) > 0
+			if scan != ids[task.ID] {
+				t.Fatalf("Search(%q) has %s = %v, a scan says %v (%q / %q)",
+					word, task.ID, ids[task.ID], scan, task.Content, task.Description)
+			}
+		}
+	}
+}
+
+func TestIndexInvalidatedOnChange(t *testing.T) {
+	c := NewCache(nil, &config.Config{DataFolder: t.TempDir(), RefreshRate: 24 * time.Hour})
+	c.data = testIndexData()
+	if _, ok := c.data.Index().Task("4"); ok {
+		t.Fatal("task 4 should not exist yet")
+	}
+
+	if err := c.PutTask(todoist.Task{ID: "4", Content: "new"}); err != nil {
+		t.Fatalf("PutTask: %v", err)
+	}
+	if _, ok := c.Data().Index().Task("4"); !ok {
+		t.Error("the index should include a task added by PutTask")
+	}
+
+	c.data.applySync(&todoist.SyncAllResponse{Items: []todoist.Task{{ID: "2", IsDeleted: true}}})
+	if _, ok := c.Data().Index().Task("2"); ok {
+		t.Error("the index should drop a task deleted by a sync")
+	}
+}
+
+func BenchmarkBuildIndex(b *testing.B) {
+	f := todoisttest.NewFixture(10000, time.Now())
+	d := &CachedData{Tasks: f.Tasks, Projects: f.Projects, Sections: f.Sections, Labels: f.Labels, Notes: f.Notes}
+	b.ResetTimer()
+	for range b.N {
+		d.invalidateIndex()
+		d.Index()
+	}
+}
+
+func BenchmarkIndexWords(b *testing.B) {
+	f := todoisttest.NewFixture(10000, time.Now())
+	idx := (&CachedData{Tasks: f.Tasks}).Index()
+	b.ResetTimer()
+	for range b.N {
+		idx.indexWords()
+	}
+}
+
+func BenchmarkIndexSearch(b *testing.B) {
+	f := todoisttest.NewFixture(10000, time.Now())
+	idx := (&CachedData{Tasks: f.Tasks}).Index()
+	idx.indexWords()
+	b.ResetTimer()
+	for range b.N {
+		idx.Search("reprot")
+	}
+}
+
+func BenchmarkScanSearch(b *testing.B) {
+	f := todoisttest.NewFixture(10000, time.Now())
+	b.ResetTimer()
+	for range b.N {
+		for _, t := range f.Tasks {
+			_ = utils.FuzzyScore("reprot", t.Content) > 0 || utils.FuzzyScore("reprot", t.Description) > 0
+		}
+	}
+}
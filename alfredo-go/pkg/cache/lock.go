@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"alfredo-go/pkg/utils"
+	"errors"
+	"path/filepath"
+)
+
+// errLocked is returned by acquireLock when another process holds the lock
+// and we asked not to wait for it
+var errLocked = errors.New("locked by another process")
+
+// lockPath is the file refreshes, and changes to the files in the data
+// folder, take an advisory lock on
+func (c *Cache) lockPath() string {
+	return filepath.Join(c.cfg.DataFolder, "refresh.lock")
+}
+
+// withLock runs fn with the lock held, waiting for it if needed, so that
+// reading, changing and saving a file isn't interleaved with another process
+// doing the same. fn must not take the lock again. If the lock can't be
+// taken fn runs anyway, like Refresh does.
+func (c *Cache) withLock(fn func() error) error {
+	if c.cfg.DataFolder == "" {
+		return fn()
+	}
+	lock, err := acquireLock(c.lockPath(), true)
+	if err != nil {
+		utils.Log("warning: could not lock the cache, writing anyway: %v", err)
+		return fn()
+	}
+	defer lock.Unlock()
+	return fn()
+}
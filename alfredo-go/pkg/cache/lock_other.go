@@ -0,0 +1,46 @@
+//go:build !unix
+
+package cache
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// staleLockAge is how old a lock file has to be before we assume the process
+// that created it died without removing it
+const staleLockAge = 2 * time.Minute
+
+// fileLock is a lock file created exclusively, for platforms without flock(2)
+type fileLock struct {
+	path string
+}
+
+// acquireLock locks path by creating it. Unless wait is set it fails with
+// errLocked right away when another process holds the lock.
+func acquireLock(path string, wait bool) (*fileLock, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return &fileLock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if !wait {
+			return nil, errLocked
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Unlock releases the lock
+func (l *fileLock) Unlock() error {
+	return os.Remove(l.path)
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package cache
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// fileLock is an exclusive flock(2) lock, released automatically by the
+// kernel if the process dies while holding it
+type fileLock struct {
+	f *os.File
+}
+
+// acquireLock locks path, creating it if needed. Unless wait is set it fails
+// with errLocked right away when another process holds the lock.
+func acquireLock(path string, wait bool) (*fileLock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	for {
+		err = syscall.Flock(int(f.Fd()), how)
+		if !errors.Is(err, syscall.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLocked
+		}
+		return nil, err
+	}
+	return &fileLock{f: f}, nil
+}
+
+// Unlock releases the lock
+func (l *fileLock) Unlock() error {
+	syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	return l.f.Close()
+}
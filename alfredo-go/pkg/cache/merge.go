@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"alfredo-go/pkg/todoist"
+)
+
+// applySync folds a sync response into the cached data. A full sync replaces
+// every resource; an incremental one merges the changed objects by ID.
+func (d *CachedData) applySync(resp *todoist.SyncAllResponse) {
+	defer d.invalidateIndex()
+	if resp.FullSync {
+		d.Tasks, d.Projects, d.Sections, d.Labels, d.Notes, d.Filters = nil, nil, nil, nil, nil, nil
+	}
+
+	d.Tasks = mergeByID(d.Tasks, resp.Items,
+		func(t todoist.Task) string { return t.ID },
+		func(t todoist.Task) bool { return t.Checked || t.IsDeleted })
+	d.Projects = mergeByID(d.Projects, resp.Projects,
+		func(p todoist.Project) string { return p.ID },
+		func(p todoist.Project) bool { return p.IsDeleted })
+	d.Sections = mergeByID(d.Sections, resp.Sections,
+		func(s todoist.Section) string { return s.ID },
+		func(s todoist.Section) bool { return s.IsDeleted })
+	d.Labels = mergeByID(d.Labels, resp.Labels,
+		func(l todoist.Label) string { return l.ID },
+		func(l todoist.Label) bool { return l.IsDeleted })
+	d.Notes = mergeByID(d.Notes, resp.Notes,
+		func(n todoist.Note) string { return n.ID },
+		func(n todoist.Note) bool { return n.IsDeleted })
+	d.pruneNotes()
+	d.Filters = mergeByID(d.Filters, resp.Filters,
+		func(f todoist.Filter) string { return f.ID },
+		func(f todoist.Filter) bool { return f.IsDeleted })
+
+	if resp.Stats != nil {
+		d.Stats = resp.Stats
+	}
+	if resp.User != nil {
+		d.User = resp.User
+	}
+	d.SyncToken = resp.SyncToken
+}
+
+// pruneNotes drops comments on tasks that are no longer cached
+func (d *CachedData) pruneNotes() {
+	if len(d.Notes) == 0 {
+		return
+	}
+	tasks := make(map[string]bool, len(d.Tasks))
+	for _, t := range d.Tasks {
+		tasks[t.ID] = true
+	}
+	kept := d.Notes[:0]
+	for _, n := range d.Notes {
+		if tasks[n.ItemID] {
+			kept = append(kept, n)
+		}
+	}
+	d.Notes = kept
+}
+
+// mergeByID updates cached objects with changed ones, matching on ID. Objects
+// for which removed returns true are dropped; unknown ones are appended in
+// server order.
+func mergeByID[T any](cached, changed []T, id func(T) string, removed func(T) bool) []T {
+	index := make(map[string]int, len(cached))
+	for i, v := range cached {
+		index[id(v)] = i
+	}
+
+	gone := make(map[string]bool)
+	for _, v := range changed {
+		if removed(v) {
+			gone[id(v)] = true
+			continue
+		}
+		if i, ok := index[id(v)]; ok {
+			cached[i] = v
+		} else {
+			index[id(v)] = len(cached)
+			cached = append(cached, v)
+		}
+	}
+	if len(gone) == 0 {
+		return cached
+	}
+
+	result := cached[:0]
+	for _, v := range cached {
+		if !gone[id(v)] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
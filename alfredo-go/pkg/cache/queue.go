@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (c *Cache) queuePath() string {
+	return filepath.Join(c.cfg.DataFolder, "queue.json")
+}
+
+// LoadQueue reads the commands waiting to be replayed, oldest first.
+// A missing queue file means nothing is pending.
+func (c *Cache) LoadQueue() ([]todoist.Command, error) {
+	if c.cfg.DataFolder == "" {
+		return nil, nil
+	}
+	f, err := os.Open(c.queuePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cmds []todoist.Command
+	if err := json.NewDecoder(f).Decode(&cmds); err != nil {
+		return nil, fmt.Errorf("failed to decode queue: %w", err)
+	}
+	return cmds, nil
+}
+
+// SaveQueue replaces the pending commands; an empty slice removes the queue file
+func (c *Cache) SaveQueue(cmds []todoist.Command) error {
+	if c.cfg.DataFolder == "" {
+		return errors.New("no data folder to store the queue in")
+	}
+	if len(cmds) == 0 {
+		err := os.Remove(c.queuePath())
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return saveJSON(c.queuePath(), cmds)
+}
+
+// ReplayQueue passes the queued commands to send with the lock held, so
+// that none are queued in the meantime, and then removes those send returned
+// a status for, applied or rejected. Commands without one, because send
+// stopped part way, stay queued. It returns the commands that were pending
+// along with send's result.
+func (c *Cache) ReplayQueue(send func([]todoist.Command) (*todoist.CommandsResponse, error)) ([]todoist.Command, *todoist.CommandsResponse, error) {
+	var pending []todoist.Command
+	var resp *todoist.CommandsResponse
+	err := c.withLock(func() error {
+		var err error
+		if pending, err = c.LoadQueue(); err != nil || len(pending) == 0 {
+			return err
+		}
+		resp, err = send(pending)
+		if resp == nil {
+			return err
+		}
+		var left []todoist.Command
+		for _, cmd := range pending {
+			if _, sent := resp.SyncStatus[cmd.UUID]; !sent {
+				left = append(left, cmd)
+			}
+		}
+		if len(left) < len(pending) {
+			if sErr := c.SaveQueue(left); sErr != nil {
+				return errors.Join(err, sErr)
+			}
+		}
+		return err
+	})
+	return pending, resp, err
+}
+
+// Enqueue appends a command to the queue and applies it to the cached data
+// so that queries reflect it before it reaches the server
+func (c *Cache) Enqueue(cmd todoist.Command) error {
+	return c.withLock(func() error {
+		cmds, err := c.LoadQueue()
+		if err != nil {
+			return err
+		}
+		if err := c.SaveQueue(append(cmds, cmd)); err != nil {
+			return err
+		}
+		if err := c.applyCommand(cmd); err != nil {
+			utils.Log("warning: could not apply queued %s locally: %v", cmd.Type, err)
+		}
+		return nil
+	})
+}
+
+// commandArgs is the typed view of the item command arguments we apply locally
+type commandArgs struct {
+	ID          string          `json:"id"`
+	Content     *string         `json:"content"`
+	Description *string         `json:"description"`
+	Labels      *[]string       `json:"labels"`
+	Priority    *int            `json:"priority"`
+	ProjectID   *string         `json:"project_id"`
+	SectionID   *string         `json:"section_id"`
+	ParentID    *string         `json:"parent_id"`
+	Due         json.RawMessage `json:"due"`
+	Deadline    json.RawMessage `json:"deadline"`
+	ItemID      string          `json:"item_id"` // note_add
+}
+
+// ApplyCommand applies an item or note command to the cached data and saves it
+func (c *Cache) ApplyCommand(cmd todoist.Command) error {
+	return c.withLock(func() error { return c.applyCommand(cmd) })
+}
+
+// applyCommand is ApplyCommand with the lock held
+func (c *Cache) applyCommand(cmd todoist.Command) error {
+	if err := c.reload(); err != nil {
+		return err
+	}
+
+	// Round-trip through JSON so queued commands read back from disk
+	// (where numbers are float64 and lists are []any) decode the same way
+	raw, err := json.Marshal(cmd.Args)
+	if err != nil {
+		return err
+	}
+	var args commandArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+
+	switch cmd.Type {
+	case "item_close":
+		// A recurring task stays open; its next date is only known after a sync
+		if t, ok := c.data.Index().Task(args.ID); ok && t.Recurring() {
+			return nil
+		}
+		c.data.Tasks = removeTask(c.data.Tasks, args.ID)
+
+	case "item_delete":
+		c.data.Tasks = removeTask(c.data.Tasks, args.ID)
+
+	case "item_add":
+		task := todoist.Task{ID: cmd.TempID, Priority: 1}
+		args.applyTo(&task)
+		c.data.Tasks = append(c.data.Tasks, task)
+
+	case "note_add":
+		note := todoist.Note{
+			ID:       cmd.TempID,
+			ItemID:   args.ItemID,
+			PostedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		if args.Content != nil {
+			note.Content = *args.Content
+		}
+		c.data.Notes = append(c.data.Notes, note)
+
+	case "item_update", "item_move":
+		for i := range c.data.Tasks {
+			if c.data.Tasks[i].ID == args.ID {
+				args.applyTo(&c.data.Tasks[i])
+				break
+			}
+		}
+
+	default:
+		return nil
+	}
+	c.data.invalidateIndex()
+
+	if err := c.save(); err != nil {
+		return err
+	}
+	c.saveCounts()
+	return nil
+}
+
+func (a *commandArgs) applyTo(t *todoist.Task) {
+	if a.Content != nil {
+		t.Content = *a.Content
+	}
+	if a.Description != nil {
+		t.Description = *a.Description
+	}
+	if a.Labels != nil {
+		t.Labels = *a.Labels
+	}
+	if a.Priority != nil {
+		t.Priority = *a.Priority
+	}
+	// Moving a subtask to another project or section makes it a top-level task
+	if a.ProjectID != nil {
+		t.ProjectID = *a.ProjectID
+		t.SectionID = ""
+		t.ParentID = ""
+	}
+	if a.SectionID != nil {
+		t.SectionID = *a.SectionID
+		t.ParentID = ""
+	}
+	if a.ParentID != nil {
+		t.ParentID = *a.ParentID
+	}
+	if a.Due != nil {
+		t.Due = nil
+		json.Unmarshal(a.Due, &t.Due)
+	}
+	if a.Deadline != nil {
+		t.Deadline = nil
+		json.Unmarshal(a.Deadline, &t.Deadline)
+	}
+}
+
+func removeTask(tasks []todoist.Task, id string) []todoist.Task {
+	result := tasks[:0]
+	for _, t := range tasks {
+		if t.ID != id {
+			result = append(result, t)
+		}
+	}
+	return result
+}
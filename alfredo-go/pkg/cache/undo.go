@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"alfredo-go/pkg/todoist"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxUndoEntries caps how many actions the undo journal remembers
+const maxUndoEntries = 20
+
+// UndoEntry records the state of tasks right before a mutation so that it
+// can be reverted later
+type UndoEntry struct {
+	Action string         `json:"action"` // complete, delete, reschedule, edit, or a bulk action
+	Tasks  []todoist.Task `json:"tasks"`
+	At     time.Time      `json:"at"`
+
+	// Open subtasks and comments deleted along with Tasks, which can't be
+	// restored
+	Subtasks int `json:"subtasks,omitempty"`
+	Comments int `json:"comments,omitempty"`
+}
+
+func (c *Cache) undoPath() string {
+	return filepath.Join(c.cfg.DataFolder, "undo.json")
+}
+
+// LoadUndo reads the undo journal, oldest entry first
+func (c *Cache) LoadUndo() ([]UndoEntry, error) {
+	if c.cfg.DataFolder == "" {
+		return nil, nil
+	}
+	f, err := os.Open(c.undoPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []UndoEntry
+	if err := json.NewDecoder(f).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode undo journal: %w", err)
+	}
+	return entries, nil
+}
+
+// SaveUndo replaces the undo journal
+func (c *Cache) SaveUndo(entries []UndoEntry) error {
+	return c.withLock(func() error { return c.saveUndo(entries) })
+}
+
+func (c *Cache) saveUndo(entries []UndoEntry) error {
+	if c.cfg.DataFolder == "" {
+		return nil
+	}
+	if len(entries) > maxUndoEntries {
+		entries = entries[len(entries)-maxUndoEntries:]
+	}
+	return saveJSON(c.undoPath(), entries)
+}
+
+// PushUndo appends an entry to the undo journal
+func (c *Cache) PushUndo(entry UndoEntry) error {
+	return c.withLock(func() error {
+		entries, err := c.LoadUndo()
+		if err != nil {
+			entries = nil // a broken journal shouldn't block new entries
+		}
+		return c.saveUndo(append(entries, entry))
+	})
+}
+
+// RemoveUndo removes an entry from the undo journal once it was undone,
+// keeping any pushed since it was read
+func (c *Cache) RemoveUndo(entry UndoEntry) error {
+	return c.withLock(func() error {
+		entries, err := c.LoadUndo()
+		if err != nil {
+			return err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Action == entry.Action && entries[i].At.Equal(entry.At) {
+				return c.saveUndo(append(entries[:i], entries[i+1:]...))
+			}
+		}
+		return nil
+	})
+}
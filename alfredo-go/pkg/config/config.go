@@ -1,8 +1,14 @@
 package config
 
 import (
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/utils"
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds the application configuration
@@ -10,13 +16,40 @@ type Config struct {
 	Token        string
 	ShowGoals    bool
 	PartialMatch bool
-	RefreshRate  int    // days between auto-refresh
-	TaskOpen     string // "app" or "browser"
+	RefreshRate  time.Duration // max age of the cache before an auto-refresh
+	TaskOpen     string        // "app" or "browser"
 	DataFolder   string
 	DueLang      string // language for Todoist NLP dates (e.g., "en", "de")
 	TaskStamp    string // template for task description (supports {timestamp} placeholder)
+	BaseURL      string // Todoist API base URL, overridable for testing or proxies
+	Timezone     string // IANA timezone for due dates; empty uses the Todoist account's
+	NowHours     int    // look-ahead of the now query mode, in hours
+	UpcomingDays int    // look-ahead of the upcoming query mode, in days
+
+	// ModeMaxAge overrides RefreshRate for some query modes, e.g. to keep
+	// today always fresh
+	ModeMaxAge map[string]time.Duration
+
+	CacheFormat string // CacheFormatJSON or CacheFormatGob
 }
 
+// Formats the cached Todoist data can be stored in: readable JSON, or gob,
+// which is several times smaller and faster to load
+const (
+	CacheFormatJSON = "json"
+	CacheFormatGob  = "gob"
+)
+
+// DefaultRefreshRate is the max age of the cache unless RefreshRate is set
+const DefaultRefreshRate = 24 * time.Hour
+
+// DefaultNowHours is the look-ahead of the now query mode unless NOW_HOURS is set
+const DefaultNowHours = 2
+
+// DefaultUpcomingDays is the look-ahead of the upcoming query mode unless
+// UPCOMING_DAYS is set
+const DefaultUpcomingDays = 7
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	token := os.Getenv("TOKEN")
@@ -31,7 +64,7 @@ func LoadConfig() *Config {
 
 	showGoals := envIntBool("SHOW_GOALS", true)
 	partialMatch := envIntBool("PARTIAL_MATCH", true)
-	refreshRate := envInt("RefreshRate", 1)
+	refreshRate := envDuration("RefreshRate", DefaultRefreshRate)
 	taskOpen := os.Getenv("taskOpen")
 	if taskOpen == "" {
 		taskOpen = "browser"
@@ -45,6 +78,23 @@ func LoadConfig() *Config {
 
 	taskStamp := os.Getenv("TASK_STAMP")
 
+	baseURL := os.Getenv("TODOIST_BASE_URL")
+	if baseURL == "" {
+		baseURL = todoist.DefaultBaseURL
+	}
+
+	timezone := os.Getenv("TIMEZONE")
+	nowHours := envInt("NOW_HOURS", DefaultNowHours)
+	upcomingDays := envInt("UPCOMING_DAYS", DefaultUpcomingDays)
+	modeMaxAge, err := ParseModeMaxAge(os.Getenv("MODE_MAX_AGE"))
+	if err != nil {
+		utils.Log("warning: ignoring part of MODE_MAX_AGE: %v", err)
+	}
+	cacheFormat := CacheFormatJSON
+	if strings.EqualFold(os.Getenv("CACHE_FORMAT"), CacheFormatGob) {
+		cacheFormat = CacheFormatGob
+	}
+
 	return &Config{
 		Token:        token,
 		ShowGoals:    showGoals,
@@ -54,6 +104,12 @@ func LoadConfig() *Config {
 		DataFolder:   dataFolder,
 		DueLang:      dueLang,
 		TaskStamp:    taskStamp,
+		BaseURL:      baseURL,
+		Timezone:     timezone,
+		NowHours:     nowHours,
+		UpcomingDays: upcomingDays,
+		ModeMaxAge:   modeMaxAge,
+		CacheFormat:  cacheFormat,
 	}
 }
 
@@ -62,6 +118,63 @@ func (c *Config) GetToken() string {
 	return c.Token
 }
 
+// MaxAge returns how old the cache may be when querying in mode
+func (c *Config) MaxAge(mode string) time.Duration {
+	if age, ok := c.ModeMaxAge[mode]; ok {
+		return age
+	}
+	return c.RefreshRate
+}
+
+// ParseMaxAge parses a cache age: a number of days as RefreshRate always
+// was ("1"), or a duration with a unit of m, h, d or w ("15m", "2h", "1d"),
+// or a Go duration ("1h30m")
+func ParseMaxAge(v string) (time.Duration, error) {
+	v = strings.TrimSpace(v)
+	num, unit := v, 24*time.Hour
+	switch {
+	case strings.HasSuffix(v, "d"):
+		num = strings.TrimSuffix(v, "d")
+	case strings.HasSuffix(v, "w"):
+		num, unit = strings.TrimSuffix(v, "w"), 7*24*time.Hour
+	}
+
+	var d time.Duration
+	if n, err := strconv.Atoi(num); err == nil {
+		d = time.Duration(n) * unit
+	} else if d, err = time.ParseDuration(v); err != nil {
+		return 0, fmt.Errorf("invalid age %q", v)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative age %q", v)
+	}
+	return d, nil
+}
+
+// ParseModeMaxAge parses per-mode cache ages like "today=0, now=5m, all=1d".
+// Entries that can't be parsed are skipped and reported together.
+func ParseModeMaxAge(v string) (map[string]time.Duration, error) {
+	ages := map[string]time.Duration{}
+	var errs []error
+	for _, entry := range strings.Split(v, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		mode, age, ok := strings.Cut(entry, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("%q is not mode=age", strings.TrimSpace(entry)))
+			continue
+		}
+		d, err := ParseMaxAge(age)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ages[strings.ToLower(strings.TrimSpace(mode))] = d
+	}
+	return ages, errors.Join(errs...)
+}
+
 func envIntBool(key string, defaultVal bool) bool {
 	v := os.Getenv(key)
 	if v == "" {
@@ -115,6 +228,19 @@ func indexByte(s string, c byte) int {
 	return -1
 }
 
+func envDuration(key string, defaultVal time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultVal
+	}
+	d, err := ParseMaxAge(v)
+	if err != nil {
+		utils.Log("warning: %s: %v, using %v", key, err, defaultVal)
+		return defaultVal
+	}
+	return d
+}
+
 func envInt(key string, defaultVal int) int {
 	v := os.Getenv(key)
 	if v == "" {
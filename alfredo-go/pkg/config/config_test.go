@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -36,3 +37,108 @@ func TestGetToken(t *testing.T) {
 		t.Errorf("Expected token %s, got %s", expectedToken, config.GetToken())
 	}
 }
+
+func TestLoadConfigTimezone(t *testing.T) {
+	os.Unsetenv("TIMEZONE")
+	if tz := LoadConfig().Timezone; tz != "" {
+		t.Errorf("Expected empty timezone, got %s", tz)
+	}
+
+	os.Setenv("TIMEZONE", "Europe/Rome")
+	defer os.Unsetenv("TIMEZONE")
+	if tz := LoadConfig().Timezone; tz != "Europe/Rome" {
+		t.Errorf("Expected timezone Europe/Rome, got %s", tz)
+	}
+}
+
+func TestParseMaxAge(t *testing.T) {
+	tests := []struct {
+		v    string
+		want time.Duration
+	}{
+		{"0", 0},
+		{"1", 24 * time.Hour},
+		{"3", 72 * time.Hour},
+		{"15m", 15 * time.Minute},
+		{"2h", 2 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+		{" 30s ", 30 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := ParseMaxAge(tt.v)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMaxAge(%q) = %v, %v; want %v", tt.v, got, err, tt.want)
+		}
+	}
+	for _, v := range []string{"", "soon", "-1", "-2h", "d", "1.5d"} {
+		if got, err := ParseMaxAge(v); err == nil {
+			t.Errorf("ParseMaxAge(%q) = %v, want an error", v, got)
+		}
+	}
+}
+
+func TestParseModeMaxAge(t *testing.T) {
+	ages, err := ParseModeMaxAge("today=0, Now = 5m,all=1,bogus,due=later")
+	if err == nil {
+		t.Error("expected an error for the malformed entries")
+	}
+	want := map[string]time.Duration{"today": 0, "now": 5 * time.Minute, "all": 24 * time.Hour}
+	if len(ages) != len(want) {
+		t.Fatalf("ages = %v, want %v", ages, want)
+	}
+	for mode, age := range want {
+		if ages[mode] != age {
+			t.Errorf("ages[%q] = %v, want %v", mode, ages[mode], age)
+		}
+	}
+}
+
+func TestLoadConfigRefreshRate(t *testing.T) {
+	defer os.Unsetenv("RefreshRate")
+	defer os.Unsetenv("MODE_MAX_AGE")
+
+	tests := []struct {
+		v    string
+		want time.Duration
+	}{
+		{"", DefaultRefreshRate},
+		{"2", 48 * time.Hour},
+		{"15m", 15 * time.Minute},
+		{"nonsense", DefaultRefreshRate},
+	}
+	for _, tt := range tests {
+		os.Setenv("RefreshRate", tt.v)
+		if got := LoadConfig().RefreshRate; got != tt.want {
+			t.Errorf("RefreshRate=%q gives %v, want %v", tt.v, got, tt.want)
+		}
+	}
+
+	os.Setenv("RefreshRate", "2h")
+	os.Setenv("MODE_MAX_AGE", "today=0")
+	cfg := LoadConfig()
+	if age := cfg.MaxAge("today"); age != 0 {
+		t.Errorf("MaxAge(today) = %v, want 0", age)
+	}
+	if age := cfg.MaxAge("all"); age != 2*time.Hour {
+		t.Errorf("MaxAge(all) = %v, want the refresh rate", age)
+	}
+}
+
+func TestLoadConfigCacheFormat(t *testing.T) {
+	defer os.Unsetenv("CACHE_FORMAT")
+
+	for v, want := range map[string]string{
+		"":      CacheFormatJSON,
+		"gob":   CacheFormatGob,
+		"GOB":   CacheFormatGob,
+		"json":  CacheFormatJSON,
+		"bogus": CacheFormatJSON,
+	} {
+		os.Setenv("CACHE_FORMAT", v)
+		if got := LoadConfig().CacheFormat; got != want {
+			t.Errorf("CACHE_FORMAT=%q gives %q, want %q", v, got, want)
+		}
+	}
+}
@@ -1,11 +1,10 @@
 package todoist
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
@@ -15,24 +14,50 @@ type Client struct {
 	token      string
 	httpClient *http.Client
 	baseURL    string
+	maxRetries int
+	backoff    time.Duration
+	sleep      func(time.Duration)
 }
 
 // Task represents a Todoist task
 type Task struct {
 	ID          string    `json:"id"`
 	Content     string    `json:"content"`
+	Description string    `json:"description"`
 	Due         *Due      `json:"due"`
 	Deadline    *Deadline `json:"deadline"`
 	Labels      []string  `json:"labels"`
 	Priority    int       `json:"priority"`
 	ProjectID   string    `json:"project_id"`
 	SectionID   string    `json:"section_id"`
+	ParentID    string    `json:"parent_id"`
+	ChildOrder  int       `json:"child_order"`
 	IsRecurring bool      `json:"is_recurring"`
+	Checked     bool      `json:"checked,omitempty"`
+	IsDeleted   bool      `json:"is_deleted,omitempty"`
+
+	// ResponsibleUID is the user a task in a shared project is assigned to
+	ResponsibleUID string `json:"responsible_uid,omitempty"`
 }
 
-// Due represents a task's due date
-type Due struct {
-	Date string `json:"date"`
+// Recurring reports whether the task repeats
+func (t Task) Recurring() bool {
+	return t.IsRecurring || (t.Due != nil && t.Due.IsRecurring)
+}
+
+// Reschedule returns due arguments for the Sync API moving the task to date.
+// For a recurring task the recurrence rule is sent along, so only the current
+// occurrence moves instead of the task becoming a one-off.
+func (t Task) Reschedule(date string) map[string]any {
+	due := map[string]any{"date": date}
+	if t.Recurring() && t.Due != nil && t.Due.String != "" {
+		due["string"] = t.Due.String
+		due["is_recurring"] = true
+		if t.Due.Lang != "" {
+			due["lang"] = t.Due.Lang
+		}
+	}
+	return due
 }
 
 // Deadline represents a task's deadline
@@ -45,15 +70,18 @@ type Deadline struct {
 type Project struct {
 	ID         string `json:"id"`
 	Name       string `json:"name"`
+	ParentID   string `json:"parent_id,omitempty"`
 	IsDeleted  bool   `json:"is_deleted"`
 	IsArchived bool   `json:"is_archived"`
 }
 
 // Section represents a Todoist section
 type Section struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	ProjectID string `json:"project_id"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	ProjectID  string `json:"project_id"`
+	IsDeleted  bool   `json:"is_deleted,omitempty"`
+	IsArchived bool   `json:"is_archived,omitempty"`
 }
 
 // Label represents a Todoist label
@@ -63,6 +91,25 @@ type Label struct {
 	IsDeleted bool   `json:"is_deleted"`
 }
 
+// Filter is a filter saved in Todoist; Query uses Todoist's filter language
+type Filter struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Query      string `json:"query"`
+	ItemOrder  int    `json:"item_order"`
+	IsFavorite bool   `json:"is_favorite,omitempty"`
+	IsDeleted  bool   `json:"is_deleted,omitempty"`
+}
+
+// Note represents a comment on a task
+type Note struct {
+	ID        string `json:"id"`
+	ItemID    string `json:"item_id"`
+	Content   string `json:"content"`
+	PostedAt  string `json:"posted_at"`
+	IsDeleted bool   `json:"is_deleted,omitempty"`
+}
+
 // StatsResponse represents the response from the stats API
 type StatsResponse struct {
 	DaysItems []DayItem  `json:"days_items"`
@@ -89,50 +136,87 @@ type Goals struct {
 
 // UserInfo holds daily/weekly goal info from sync API
 type UserInfo struct {
-	DailyGoal  int `json:"daily_goal"`
-	WeeklyGoal int `json:"weekly_goal"`
+	ID         string `json:"id"`
+	DailyGoal  int    `json:"daily_goal"`
+	WeeklyGoal int    `json:"weekly_goal"`
+	TZInfo     TZInfo `json:"tz_info"`
 }
 
-// SyncAllResponse represents the full sync API response
+// TZInfo is the timezone set in the user's Todoist settings
+type TZInfo struct {
+	Timezone string `json:"timezone"`
+}
+
+// SyncAllResponse represents the sync API response. When FullSync is false the
+// resource slices only hold objects changed since the sync token that was sent.
 type SyncAllResponse struct {
-	Items    []Task         `json:"items"`
-	Projects []Project      `json:"projects"`
-	Sections []Section      `json:"sections"`
-	Labels   []Label        `json:"labels"`
-	Stats    *StatsResponse `json:"stats"`
-	User     *UserInfo      `json:"user"`
+	SyncToken string         `json:"sync_token"`
+	FullSync  bool           `json:"full_sync"`
+	Items     []Task         `json:"items"`
+	Projects  []Project      `json:"projects"`
+	Sections  []Section      `json:"sections"`
+	Labels    []Label        `json:"labels"`
+	Notes     []Note         `json:"notes"`
+	Filters   []Filter       `json:"filters"`
+	Stats     *StatsResponse `json:"stats"`
+	User      *UserInfo      `json:"user"`
 }
 
-// NewClient creates a new Todoist API client
-func NewClient(token string) *Client {
+// DefaultBaseURL is the public Todoist API
+const DefaultBaseURL = "https://api.todoist.com"
+
+// NewClient creates a new Todoist API client. An empty baseURL means
+// DefaultBaseURL.
+func NewClient(token, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
 	return &Client{
 		token:      token,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
-		baseURL:    "https://api.todoist.com",
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+		sleep:      time.Sleep,
 	}
 }
 
+// SetRetries changes how often idempotent requests are retried and the base
+// delay between attempts
+func (c *Client) SetRetries(maxRetries int, backoff time.Duration) {
+	c.maxRetries = maxRetries
+	c.backoff = backoff
+}
+
 // SyncAll fetches all data in a single API call via the Sync endpoint
 func (c *Client) SyncAll() (*SyncAllResponse, error) {
-	form := `sync_token=*&resource_types=["all"]`
-	req, err := http.NewRequest("POST", c.baseURL+"/api/v1/sync", strings.NewReader(form))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Sync("*")
+}
 
-	resp, err := c.httpClient.Do(req)
+// Sync fetches everything changed since syncToken via the Sync endpoint.
+// A token of "*" (or "") requests a full sync.
+func (c *Client) Sync(syncToken string) (*SyncAllResponse, error) {
+	if syncToken == "" {
+		syncToken = "*"
+	}
+	form := url.Values{
+		"sync_token":     {syncToken},
+		"resource_types": {`["all"]`},
+	}.Encode()
+
+	resp, err := c.do(request{
+		op:          "sync",
+		method:      "POST",
+		path:        "/api/v1/sync",
+		body:        []byte(form),
+		contentType: "application/x-www-form-urlencoded",
+		idempotent:  true,
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("sync failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
 	var syncResp SyncAllResponse
 	if err := json.NewDecoder(resp.Body).Decode(&syncResp); err != nil {
 		return nil, fmt.Errorf("failed to decode sync response: %w", err)
@@ -140,50 +224,39 @@ func (c *Client) SyncAll() (*SyncAllResponse, error) {
 	return &syncResp, nil
 }
 
-// CompleteTask marks a task as completed
+// CompleteTask marks a task as completed. It isn't retried: closing a
+// recurring task twice would skip an occurrence.
 func (c *Client) CompleteTask(taskID string) error {
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/tasks/%s/close", c.baseURL, taskID), nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(request{
+		op:     "complete task",
+		method: "POST",
+		path:   fmt.Sprintf("/api/v1/tasks/%s/close", taskID),
+	})
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to complete task: status %d, body: %s", resp.StatusCode, string(body))
-	}
+	resp.Body.Close()
 	return nil
 }
 
-// DeleteTask deletes a task
+// DeleteTask deletes a task. It isn't retried, since a retry after a
+// deletion that went through would report ErrNotFound.
 func (c *Client) DeleteTask(taskID string) error {
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/api/v1/tasks/%s", c.baseURL, taskID), nil)
+	resp, err := c.do(request{
+		op:     "delete task",
+		method: "DELETE",
+		path:   fmt.Sprintf("/api/v1/tasks/%s", taskID),
+	})
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete task: status %d, body: %s", resp.StatusCode, string(body))
-	}
+	resp.Body.Close()
 	return nil
 }
 
-// CreateTask creates a new task via the REST API
-func (c *Client) CreateTask(content string, labels []string, projectID, sectionID, dueDate, dueString, dueLang string, priority int, deadline *Deadline, description string) error {
+// CreateTask creates a new task via the REST API and returns it as Todoist
+// stored it, with any due string resolved to a date
+func (c *Client) CreateTask(content string, labels []string, projectID, sectionID, parentID, dueDate, dueString, dueLang string, priority int, deadline *Deadline, description string) (*Task, error) {
 	payload := map[string]any{
 		"content":  content,
 		"priority": priority,
@@ -200,6 +273,9 @@ func (c *Client) CreateTask(content string, labels []string, projectID, sectionI
 	if sectionID != "" {
 		payload["section_id"] = sectionID
 	}
+	if parentID != "" {
+		payload["parent_id"] = parentID
+	}
 	if dueString != "" {
 		// Use Todoist's NLP: send due_string + due_lang
 		payload["due_string"] = dueString
@@ -222,68 +298,39 @@ func (c *Client) CreateTask(content string, labels []string, projectID, sectionI
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest("POST", c.baseURL+"/api/v1/tasks", bytes.NewReader(body))
-	if err != nil {
-		return err
+		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	// The request ID makes retries safe: Todoist won't create the task twice
+	resp, err := c.do(request{
+		op:          "create task",
+		method:      "POST",
+		path:        "/api/v1/tasks",
+		body:        body,
+		contentType: "application/json",
+		requestID:   NewUUID(),
+		idempotent:  true,
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create task: status %d, body: %s", resp.StatusCode, string(respBody))
+	var task Task
+	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
+		return nil, fmt.Errorf("failed to decode created task: %w", err)
 	}
-	return nil
+	return &task, nil
 }
 
 // UpdateTask updates a task via the Sync API (item_update command)
 func (c *Client) UpdateTask(taskID string, updates map[string]any) error {
-	args := map[string]any{"id": taskID}
-	for k, v := range updates {
-		args[k] = v
-	}
-
-	commands := []map[string]any{
-		{
-			"type": "item_update",
-			"uuid": fmt.Sprintf("%d", time.Now().UnixNano()),
-			"args": args,
-		},
-	}
-
-	cmdJSON, err := json.Marshal(commands)
+	cmd := ItemUpdate(taskID, updates)
+	resp, err := c.ExecuteCommands([]Command{cmd})
 	if err != nil {
 		return err
 	}
-
-	form := "commands=" + string(cmdJSON)
-	req, err := http.NewRequest("POST", c.baseURL+"/api/v1/sync", strings.NewReader(form))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update task: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-	return nil
+	return resp.Err(cmd.UUID)
 }
 
 // CreateLabel creates a new label via the REST API
@@ -294,22 +341,16 @@ func (c *Client) CreateLabel(name string) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/api/v1/labels", bytes.NewReader(body))
+	resp, err := c.do(request{
+		op:          "create label",
+		method:      "POST",
+		path:        "/api/v1/labels",
+		body:        body,
+		contentType: "application/json",
+	})
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create label: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
+	resp.Body.Close()
 	return nil
 }
@@ -0,0 +1,243 @@
+package todoist_test
+
+import (
+	"alfredo-go/pkg/todoist"
+	"alfredo-go/pkg/todoist/todoisttest"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testToken = "test-token"
+
+func newTestClient(t *testing.T) (*todoist.Client, *todoisttest.Server) {
+	t.Helper()
+	srv := todoisttest.NewServer(testToken)
+	t.Cleanup(srv.Close)
+	client := todoist.NewClient(testToken, srv.URL)
+	client.SetRetries(2, 0)
+	return client, srv
+}
+
+func TestSync_Incremental(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddTask(todoist.Task{ID: "1", Content: "first"})
+
+	full, err := client.SyncAll()
+	if err != nil {
+		t.Fatalf("SyncAll: %v", err)
+	}
+	if len(full.Items) != 1 || !full.FullSync {
+		t.Fatalf("full sync = %d items (full=%v), want 1 item", len(full.Items), full.FullSync)
+	}
+
+	srv.AddTask(todoist.Task{ID: "2", Content: "second"})
+	if err := client.CompleteTask("1"); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+
+	inc, err := client.Sync(full.SyncToken)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if inc.FullSync {
+		t.Error("expected an incremental sync")
+	}
+	if len(inc.Items) != 2 {
+		t.Fatalf("incremental sync returned %d items, want 2", len(inc.Items))
+	}
+	for _, item := range inc.Items {
+		if item.ID == "1" && !item.Checked {
+			t.Error("completed task should be reported as checked")
+		}
+	}
+}
+
+func TestExecuteCommands_TempIDMapping(t *testing.T) {
+	client, srv := newTestClient(t)
+
+	add := todoist.ItemAdd(todoist.NewUUID(), map[string]any{"content": "new task"})
+	update := todoist.ItemUpdate(add.TempID, map[string]any{"priority": 4})
+	bad := todoist.ItemClose("missing")
+
+	resp, err := client.ExecuteCommands([]todoist.Command{add, update, bad})
+	if err != nil {
+		t.Fatalf("ExecuteCommands: %v", err)
+	}
+	if err := resp.Err(add.UUID); err != nil {
+		t.Errorf("item_add failed: %v", err)
+	}
+	if err := resp.Err(update.UUID); err != nil {
+		t.Errorf("item_update failed: %v", err)
+	}
+	if err := resp.Err(bad.UUID); err == nil {
+		t.Error("closing an unknown task should fail")
+	}
+
+	id := resp.ResolveID(add.TempID)
+	task, ok := srv.Task(id)
+	if !ok || id == add.TempID {
+		t.Fatalf("temp ID %s was not mapped to a real task", add.TempID)
+	}
+	if task.Priority != 4 {
+		t.Errorf("priority = %d, want 4", task.Priority)
+	}
+}
+
+func TestExecuteCommands_KeepsTempIDsInOneRequest(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddTask(todoist.Task{ID: "1", Content: "task"})
+
+	// 150 commands: a task added at 90 and updated up to 130 straddles the
+	// 100-command request limit
+	var cmds []todoist.Command
+	for i := range 150 {
+		switch {
+		case i == 90:
+			cmds = append(cmds, todoist.ItemAdd("tmp", map[string]any{"content": "new"}))
+		case i > 90 && i <= 130:
+			cmds = append(cmds, todoist.ItemUpdate("tmp", map[string]any{"priority": 1 + i%4}))
+		default:
+			cmds = append(cmds, todoist.ItemUpdate("1", map[string]any{"priority": 1 + i%4}))
+		}
+	}
+	resp, err := client.ExecuteCommands(cmds)
+	if err != nil {
+		t.Fatalf("ExecuteCommands: %v", err)
+	}
+	for i, cmd := range cmds {
+		if err := resp.Err(cmd.UUID); err != nil {
+			t.Fatalf("command %d (%s) failed: %v", i, cmd.Type, err)
+		}
+	}
+	if _, ok := srv.Task(resp.ResolveID("tmp")); !ok {
+		t.Error("temp ID was not mapped to a real task")
+	}
+}
+
+func TestExecuteCommands_ReturnsAppliedOnFailure(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddTask(todoist.Task{ID: "1", Content: "task"})
+	srv.FailNext(0, http.StatusBadRequest)
+
+	var cmds []todoist.Command
+	for range 150 {
+		cmds = append(cmds, todoist.ItemUpdate("1", map[string]any{"priority": 2}))
+	}
+	resp, err := client.ExecuteCommands(cmds)
+	if err == nil {
+		t.Fatal("ExecuteCommands should fail with the second request")
+	}
+	if resp == nil || len(resp.SyncStatus) != 100 {
+		t.Fatalf("want the statuses of the 100 applied commands, got %v", resp)
+	}
+	if _, ok := resp.SyncStatus[cmds[120].UUID]; ok {
+		t.Error("a command of the failed request has a status")
+	}
+}
+
+func TestDo_RetriesServerErrors(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddTask(todoist.Task{ID: "1", Content: "task"})
+	srv.FailNext(http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	// Commands carry UUIDs, so resending them is safe
+	closeCmd := todoist.ItemClose("1")
+	resp, err := client.ExecuteCommands([]todoist.Command{closeCmd})
+	if err != nil {
+		t.Fatalf("ExecuteCommands should succeed after retries: %v", err)
+	}
+	if err := resp.Err(closeCmd.UUID); err != nil {
+		t.Fatalf("item_close failed: %v", err)
+	}
+	if task, _ := srv.Task("1"); !task.Checked {
+		t.Error("task was not completed")
+	}
+}
+
+func TestDo_DoesNotRetryCloseOrDelete(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddTask(todoist.Task{ID: "1", Content: "task"})
+
+	srv.FailNext(http.StatusInternalServerError)
+	if err := client.CompleteTask("1"); !errors.Is(err, todoist.ErrServer) {
+		t.Errorf("CompleteTask err = %v, want ErrServer without a retry", err)
+	}
+	srv.FailNext(http.StatusBadGateway)
+	if err := client.DeleteTask("1"); !errors.Is(err, todoist.ErrServer) {
+		t.Errorf("DeleteTask err = %v, want ErrServer without a retry", err)
+	}
+	if task, _ := srv.Task("1"); task.Checked || task.IsDeleted {
+		t.Errorf("task = %+v, want it untouched", task)
+	}
+}
+
+func TestDo_GivesUpAfterMaxRetries(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.FailNext(500, 500, 500)
+
+	_, err := client.SyncAll()
+	if !errors.Is(err, todoist.ErrServer) {
+		t.Fatalf("err = %v, want ErrServer", err)
+	}
+	var apiErr *todoist.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 {
+		t.Errorf("err = %v, want an *APIError with status 500", err)
+	}
+}
+
+func TestDo_RetriesRateLimit(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.FailNext(http.StatusTooManyRequests)
+
+	// Label creation isn't idempotent, but a 429 means it wasn't processed
+	if err := client.CreateLabel("waiting"); err != nil {
+		t.Fatalf("CreateLabel should succeed after a 429: %v", err)
+	}
+	if labels := srv.Labels(); len(labels) != 1 || labels[0] != "waiting" {
+		t.Errorf("labels = %v, want [waiting]", labels)
+	}
+}
+
+func TestDo_Unauthorized(t *testing.T) {
+	_, srv := newTestClient(t)
+	client := todoist.NewClient("wrong-token", srv.URL)
+
+	_, err := client.SyncAll()
+	if !errors.Is(err, todoist.ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+	if todoist.IsNetworkError(err) {
+		t.Error("an HTTP error should not count as a network error")
+	}
+}
+
+func TestDo_NetworkError(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.Close()
+
+	err := client.DeleteTask("1")
+	if !todoist.IsNetworkError(err) {
+		t.Errorf("err = %v, want a network error", err)
+	}
+}
+
+func TestDo_DroppedConnectionIsNotNetworkError(t *testing.T) {
+	// The request reached the server, which may have applied it
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer srv.Close()
+	client := todoist.NewClient(testToken, srv.URL)
+	client.SetRetries(0, 0)
+
+	err := client.DeleteTask("1")
+	if err == nil {
+		t.Fatal("DeleteTask should fail")
+	}
+	if todoist.IsNetworkError(err) {
+		t.Errorf("err = %v should not count as Todoist being unreachable", err)
+	}
+}
@@ -0,0 +1,271 @@
+package todoist
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Command is a single Sync API write command. The UUID makes it idempotent:
+// Todoist ignores a command whose UUID it has already processed.
+type Command struct {
+	Type   string         `json:"type"`
+	UUID   string         `json:"uuid"`
+	TempID string         `json:"temp_id,omitempty"`
+	Args   map[string]any `json:"args"`
+}
+
+// CommandsResponse is the Sync API response to a commands request. SyncStatus
+// holds "ok" or an error object per command UUID; TempIDMapping maps the
+// temp_id of each created object to its real ID.
+type CommandsResponse struct {
+	SyncStatus    map[string]json.RawMessage `json:"sync_status"`
+	TempIDMapping map[string]string          `json:"temp_id_mapping"`
+}
+
+// CommandError is the error object Todoist reports for a failed command
+type CommandError struct {
+	Code     int    `json:"error_code"`
+	Message  string `json:"error"`
+	HTTPCode int    `json:"http_code"`
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// maxCommandsPerRequest is the Sync API limit on commands in a single request
+const maxCommandsPerRequest = 100
+
+// NewCommand builds a command of the given type with a fresh UUID
+func NewCommand(cmdType string, args map[string]any) Command {
+	return Command{Type: cmdType, UUID: NewUUID(), Args: args}
+}
+
+// ItemAdd builds an item_add command. Later commands in the same batch can
+// refer to the new task by tempID.
+func ItemAdd(tempID string, args map[string]any) Command {
+	cmd := NewCommand("item_add", args)
+	cmd.TempID = tempID
+	return cmd
+}
+
+// ItemUpdate builds an item_update command. Moving a task between projects or
+// sections needs ItemMove instead.
+func ItemUpdate(taskID string, updates map[string]any) Command {
+	args := map[string]any{"id": taskID}
+	for k, v := range updates {
+		args[k] = v
+	}
+	return NewCommand("item_update", args)
+}
+
+// ItemMove builds an item_move command. Todoist accepts exactly one target:
+// sectionID wins over projectID when both are set.
+func ItemMove(taskID, projectID, sectionID string) Command {
+	args := map[string]any{"id": taskID}
+	if sectionID != "" {
+		args["section_id"] = sectionID
+	} else {
+		args["project_id"] = projectID
+	}
+	return NewCommand("item_move", args)
+}
+
+// ItemMoveUnder builds an item_move command making the task a subtask of
+// parentID, in the parent's project and section
+func ItemMoveUnder(taskID, parentID string) Command {
+	return NewCommand("item_move", map[string]any{"id": taskID, "parent_id": parentID})
+}
+
+// ItemClose builds an item_close command, completing a task (or moving a
+// recurring task to its next occurrence)
+func ItemClose(taskID string) Command {
+	return NewCommand("item_close", map[string]any{"id": taskID})
+}
+
+// ItemUncomplete builds an item_uncomplete command, reopening a completed task
+func ItemUncomplete(taskID string) Command {
+	return NewCommand("item_uncomplete", map[string]any{"id": taskID})
+}
+
+// ItemDelete builds an item_delete command
+func ItemDelete(taskID string) Command {
+	return NewCommand("item_delete", map[string]any{"id": taskID})
+}
+
+// NoteAdd builds a note_add command adding a comment to a task
+func NoteAdd(tempID, taskID, content string) Command {
+	cmd := NewCommand("note_add", map[string]any{"item_id": taskID, "content": content})
+	cmd.TempID = tempID
+	return cmd
+}
+
+// NewUUID returns a random RFC 4122 version 4 UUID
+func NewUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Err returns the error Todoist reported for the command with the given UUID,
+// or nil if it succeeded. Failures are *CommandError values.
+func (r *CommandsResponse) Err(uuid string) error {
+	raw, ok := r.SyncStatus[uuid]
+	if !ok {
+		return fmt.Errorf("no sync status for command %s", uuid)
+	}
+	var status string
+	if json.Unmarshal(raw, &status) == nil && status == "ok" {
+		return nil
+	}
+	var cmdErr CommandError
+	if err := json.Unmarshal(raw, &cmdErr); err != nil || cmdErr.Message == "" {
+		return fmt.Errorf("command %s failed: %s", uuid, string(raw))
+	}
+	return &cmdErr
+}
+
+// ResolveID returns the real ID for a temp ID used in the batch, or id itself
+func (r *CommandsResponse) ResolveID(id string) string {
+	if real, ok := r.TempIDMapping[id]; ok {
+		return real
+	}
+	return id
+}
+
+// ExecuteCommands sends the commands to the Sync API and returns the status
+// of each one. Large batches are split into several requests, in order, but
+// never between a command creating an object and one referring to it by
+// temp ID, since Todoist only resolves temp IDs within a request. A request
+// failure stops the batch: the error is returned along with the statuses of
+// the requests already applied, and commands without a status weren't sent.
+func (c *Client) ExecuteCommands(cmds []Command) (*CommandsResponse, error) {
+	result := &CommandsResponse{
+		SyncStatus:    make(map[string]json.RawMessage, len(cmds)),
+		TempIDMapping: make(map[string]string),
+	}
+	for _, chunk := range splitCommands(cmds, maxCommandsPerRequest) {
+		resp, err := c.executeCommands(chunk)
+		if err != nil {
+			return result, err
+		}
+		for k, v := range resp.SyncStatus {
+			result.SyncStatus[k] = v
+		}
+		for k, v := range resp.TempIDMapping {
+			result.TempIDMapping[k] = v
+		}
+	}
+	return result, nil
+}
+
+// splitCommands splits cmds into chunks of at most size commands where
+// possible. A chunk only ends where no later command refers to a temp ID
+// defined before, so commands linked by temp IDs that together exceed size
+// still go in one chunk.
+func splitCommands(cmds []Command, size int) [][]Command {
+	// lastRef[i] is the index of the last command referring to the temp ID
+	// of command i
+	defined := map[string]int{}
+	lastRef := make([]int, len(cmds))
+	for i, cmd := range cmds {
+		lastRef[i] = i
+		for _, v := range cmd.Args {
+			for _, ref := range argIDs(v) {
+				if d, ok := defined[ref]; ok {
+					lastRef[d] = i
+				}
+			}
+		}
+		if cmd.TempID != "" {
+			defined[cmd.TempID] = i
+		}
+	}
+
+	var chunks [][]Command
+	start, reach, cut := 0, 0, 0
+	for i := range cmds {
+		reach = max(reach, lastRef[i])
+		if reach == i {
+			// Nothing after i refers to a temp ID defined up to i
+			cut = i + 1
+		}
+		if i+1-start >= size || i == len(cmds)-1 {
+			if cut <= start {
+				continue // no boundary yet, keep extending the chunk
+			}
+			chunks = append(chunks, cmds[start:cut])
+			start = cut
+		}
+	}
+	if start < len(cmds) {
+		chunks = append(chunks, cmds[start:])
+	}
+	return chunks
+}
+
+// argIDs returns the strings in a command argument, which may be IDs
+func argIDs(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		var ids []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+func (c *Client) executeCommands(cmds []Command) (*CommandsResponse, error) {
+	cmdJSON, err := json.Marshal(cmds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commands carry UUIDs, so Todoist ignores ones it already processed
+	form := url.Values{"commands": {string(cmdJSON)}}.Encode()
+	resp, err := c.do(request{
+		op:          "run commands",
+		method:      "POST",
+		path:        "/api/v1/sync",
+		body:        []byte(form),
+		contentType: "application/x-www-form-urlencoded",
+		idempotent:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var cmdResp CommandsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&cmdResp); err != nil {
+		return nil, fmt.Errorf("failed to decode commands response: %w", err)
+	}
+	return &cmdResp, nil
+}
+
+// IsNetworkError reports whether err means Todoist could not be reached at
+// all: the name didn't resolve or the connection was never established, so
+// the request can't have been applied. Timeouts and connections dropped
+// mid-request don't count, since Todoist may have processed the call.
+func IsNetworkError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,101 @@
+package todoist
+
+import (
+	"strings"
+	"time"
+)
+
+// Due represents a task's due date. Todoist encodes three kinds in Date:
+//   - a full-day date, "2026-10-18"
+//   - a floating date and time, "2026-10-18T09:00:00", which happens at that
+//     wall-clock time in whatever timezone the user is in
+//   - a fixed date and time in UTC, "2026-10-18T07:00:00Z", for tasks pinned
+//     to Timezone
+//
+// Datetime is set instead of (or besides) Date by some endpoints. For
+// recurring tasks Date is the current occurrence and String holds the
+// recurrence rule (e.g. "every monday").
+type Due struct {
+	Date        string `json:"date"`
+	Datetime    string `json:"datetime,omitempty"`
+	Timezone    string `json:"timezone,omitempty"`
+	String      string `json:"string,omitempty"`
+	Lang        string `json:"lang,omitempty"`
+	IsRecurring bool   `json:"is_recurring,omitempty"`
+}
+
+// In resolves the due date in loc. For a full-day date it returns midnight
+// and hasTime false. ok is false if the date can't be parsed.
+func (d *Due) In(loc *time.Location) (t time.Time, hasTime bool, ok bool) {
+	if d == nil {
+		return time.Time{}, false, false
+	}
+	value := d.Date
+	if d.Datetime != "" {
+		value = d.Datetime
+	}
+
+	if !strings.Contains(value, "T") {
+		t, err := time.ParseInLocation("2006-01-02", value, loc)
+		return t, false, err == nil
+	}
+	// Fixed: an absolute instant, shown in the user's timezone
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), true, true
+	}
+	// Floating: the same wall-clock time wherever the user is
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// Day returns the calendar day (YYYY-MM-DD) the task is due on in loc, or ""
+// if there is no valid due date
+func (d *Due) Day(loc *time.Location) string {
+	t, _, ok := d.In(loc)
+	if !ok {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// IsFloating reports whether a timed due date follows the user's timezone
+// rather than being pinned to one
+func (d *Due) IsFloating() bool {
+	value := d.Date
+	if d.Datetime != "" {
+		value = d.Datetime
+	}
+	return strings.Contains(value, "T") && !strings.HasSuffix(value, "Z") && d.Timezone == ""
+}
+
+// Zone returns the timezone a fixed due time is pinned to: Timezone, or
+// fallback if it is missing or unknown. It returns nil for full-day and
+// floating due dates.
+func (d *Due) Zone(fallback *time.Location) *time.Location {
+	if d == nil || d.IsFloating() || !strings.Contains(d.Date+d.Datetime, "T") {
+		return nil
+	}
+	if d.Timezone != "" {
+		if loc, err := time.LoadLocation(d.Timezone); err == nil {
+			return loc
+		}
+	}
+	return fallback
+}
+
+// DaysBetween returns the number of calendar days from one day to another,
+// both given as YYYY-MM-DD, ignoring DST changes in between. It returns 0 if
+// either day can't be parsed.
+func DaysBetween(from, to string) int {
+	f, err1 := time.Parse("2006-01-02", from)
+	t, err2 := time.Parse("2006-01-02", to)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	// Both are UTC midnights, so the difference is a whole number of days
+	return int(t.Sub(f).Hours() / 24)
+}
@@ -0,0 +1,126 @@
+package todoist_test
+
+import (
+	"testing"
+	"time"
+
+	"alfredo-go/pkg/todoist"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone data for %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestDueDay(t *testing.T) {
+	rome := mustLoad(t, "Europe/Rome")
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		due  todoist.Due
+		loc  *time.Location
+		want string
+	}{
+		{"full day", todoist.Due{Date: "2026-10-18"}, rome, "2026-10-18"},
+		{"full day is the same everywhere", todoist.Due{Date: "2026-10-18"}, newYork, "2026-10-18"},
+		{"floating before midnight", todoist.Due{Date: "2026-10-18T23:30:00"}, rome, "2026-10-18"},
+		{"floating keeps its wall clock", todoist.Due{Date: "2026-10-18T23:30:00"}, newYork, "2026-10-18"},
+		{"fixed crosses midnight east", todoist.Due{Date: "2026-10-18T23:30:00Z", Timezone: "UTC"}, rome, "2026-10-19"},
+		{"fixed crosses midnight west", todoist.Due{Date: "2026-10-19T02:00:00Z", Timezone: "UTC"}, newYork, "2026-10-18"},
+		{"datetime wins over date", todoist.Due{Date: "2026-10-18", Datetime: "2026-10-18T22:30:00Z"}, rome, "2026-10-19"},
+		{"floating without seconds", todoist.Due{Date: "2026-10-18T09:00"}, rome, "2026-10-18"},
+		{"invalid", todoist.Due{Date: "someday"}, rome, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.due.Day(tt.loc); got != tt.want {
+				t.Errorf("Day(%s) = %q, want %q", tt.loc, got, tt.want)
+			}
+		})
+	}
+
+	var none *todoist.Due
+	if got := none.Day(rome); got != "" {
+		t.Errorf("nil Due: Day = %q, want empty", got)
+	}
+}
+
+func TestDueInAcrossDST(t *testing.T) {
+	rome := mustLoad(t, "Europe/Rome")
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name     string
+		due      todoist.Due
+		loc      *time.Location
+		wantTime string
+	}{
+		// Europe/Rome switches to CEST at 02:00 on 2026-03-29 and back at 03:00 on 2026-10-25
+		{"rome before spring forward", todoist.Due{Date: "2026-03-28T23:30:00Z", Timezone: "Europe/Rome"}, rome, "2026-03-29T00:30"},
+		{"rome after spring forward", todoist.Due{Date: "2026-03-29T22:30:00Z", Timezone: "Europe/Rome"}, rome, "2026-03-30T00:30"},
+		{"rome after fall back", todoist.Due{Date: "2026-10-25T22:30:00Z", Timezone: "Europe/Rome"}, rome, "2026-10-25T23:30"},
+		// America/New_York switches to EDT on 2026-03-08 and back on 2026-11-01
+		{"new york before spring forward", todoist.Due{Date: "2026-03-08T04:30:00Z", Timezone: "America/New_York"}, newYork, "2026-03-07T23:30"},
+		{"new york after spring forward", todoist.Due{Date: "2026-03-09T03:30:00Z", Timezone: "America/New_York"}, newYork, "2026-03-08T23:30"},
+		{"new york after fall back", todoist.Due{Date: "2026-11-02T04:30:00Z", Timezone: "America/New_York"}, newYork, "2026-11-01T23:30"},
+		{"floating on the fall back day", todoist.Due{Date: "2026-11-01T23:30:00"}, newYork, "2026-11-01T23:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasTime, ok := tt.due.In(tt.loc)
+			if !ok || !hasTime {
+				t.Fatalf("In(%s) = ok %v, hasTime %v; want both true", tt.loc, ok, hasTime)
+			}
+			if s := got.Format("2006-01-02T15:04"); s != tt.wantTime {
+				t.Errorf("In(%s) = %s, want %s", tt.loc, s, tt.wantTime)
+			}
+		})
+	}
+
+	_, hasTime, ok := (&todoist.Due{Date: "2026-03-29"}).In(rome)
+	if !ok || hasTime {
+		t.Errorf("full day on DST change: ok %v, hasTime %v; want true, false", ok, hasTime)
+	}
+}
+
+func TestDueIsFloating(t *testing.T) {
+	tests := []struct {
+		due  todoist.Due
+		want bool
+	}{
+		{todoist.Due{Date: "2026-10-18"}, false},
+		{todoist.Due{Date: "2026-10-18T09:00:00"}, true},
+		{todoist.Due{Date: "2026-10-18T07:00:00Z", Timezone: "Europe/Rome"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.due.IsFloating(); got != tt.want {
+			t.Errorf("IsFloating(%q) = %v, want %v", tt.due.Date, got, tt.want)
+		}
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     int
+	}{
+		{"2026-10-18", "2026-10-18", 0},
+		{"2026-10-17", "2026-10-18", 1},
+		{"2026-10-18", "2026-10-17", -1},
+		// Spans both the European and US DST changes
+		{"2026-10-24", "2026-11-02", 9},
+		{"2026-03-07", "2026-03-30", 23},
+		{"2026-12-31", "2027-01-01", 1},
+		{"bad", "2026-10-18", 0},
+	}
+	for _, tt := range tests {
+		if got := todoist.DaysBetween(tt.from, tt.to); got != tt.want {
+			t.Errorf("DaysBetween(%s, %s) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package todoist
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors for API failures, matched with errors.Is on an *APIError
+var (
+	ErrUnauthorized = errors.New("todoist: invalid or missing API token")
+	ErrRateLimited  = errors.New("todoist: rate limit exceeded")
+	ErrNotFound     = errors.New("todoist: not found")
+	ErrServer       = errors.New("todoist: server error")
+)
+
+// APIError is a non-2xx response from Todoist
+type APIError struct {
+	Op         string // what we were doing, e.g. "complete task"
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("failed to %s: status %d, body: %s", e.Op, e.StatusCode, e.Body)
+}
+
+// Unwrap maps the status code onto one of the sentinel errors
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package todoist
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	// maxRetryAfter caps how long we wait on a 429 before giving up;
+	// Alfred users won't sit through a longer pause
+	maxRetryAfter = 10 * time.Second
+)
+
+// request describes a single API call made through Client.do
+type request struct {
+	op          string // for error messages, e.g. "complete task"
+	method      string
+	path        string
+	body        []byte
+	contentType string
+	requestID   string // X-Request-Id, lets Todoist deduplicate retried REST writes
+	// idempotent calls are retried on network errors and 5xx responses.
+	// 429 responses are always retried since the request wasn't processed.
+	idempotent bool
+}
+
+// do sends the request, retrying with exponential backoff where safe, and
+// returns the response of the first 2xx attempt. The caller closes the body.
+// Failures are *APIError values (see the sentinel errors) or, if Todoist
+// could not be reached, the *url.Error from the HTTP client.
+func (c *Client) do(r request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if r.body != nil {
+			body = bytes.NewReader(r.body)
+		}
+		req, err := http.NewRequest(r.method, c.baseURL+r.path, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		if r.contentType != "" {
+			req.Header.Set("Content-Type", r.contentType)
+		}
+		if r.requestID != "" {
+			req.Header.Set("X-Request-Id", r.requestID)
+		}
+
+		retriesLeft := attempt < c.maxRetries
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			// Timeouts already took long enough; don't make the user wait again
+			if retriesLeft && r.idempotent && !isTimeout(err) {
+				c.sleep(c.backoffFor(attempt))
+				continue
+			}
+			return nil, err
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		apiErr := &APIError{Op: r.op, StatusCode: resp.StatusCode, Body: string(respBody)}
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && retriesLeft:
+			wait, ok := retryAfter(resp.Header.Get("Retry-After"))
+			if !ok {
+				wait = c.backoffFor(attempt)
+			}
+			if wait > maxRetryAfter {
+				return nil, apiErr
+			}
+			c.sleep(wait)
+		case resp.StatusCode >= 500 && retriesLeft && r.idempotent:
+			c.sleep(c.backoffFor(attempt))
+		default:
+			return nil, apiErr
+		}
+	}
+}
+
+// backoffFor returns the delay before retry number attempt+1: the base
+// backoff doubled per attempt, plus up to 50% jitter
+func (c *Client) backoffFor(attempt int) time.Duration {
+	d := c.backoff << attempt
+	if d <= 0 {
+		return 0
+	}
+	return d + rand.N(d/2+1)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
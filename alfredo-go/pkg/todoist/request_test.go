@@ -0,0 +1,90 @@
+package todoist
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRetryClient returns a client for a server answering with statuses in
+// order (then 200), the requests it received and the sleeps between retries
+func newRetryClient(t *testing.T, retryAfter string, statuses ...int) (*Client, *atomic.Int32, *[]time.Duration) {
+	t.Helper()
+	requests := new(atomic.Int32)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+
+	var sleeps []time.Duration
+	c := NewClient("token", srv.URL)
+	c.SetRetries(3, 10*time.Millisecond)
+	c.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return c, requests, &sleeps
+}
+
+func TestDo_HonoursRetryAfter(t *testing.T) {
+	c, requests, sleeps := newRetryClient(t, "2", http.StatusTooManyRequests)
+
+	// 429s are retried even for calls that aren't idempotent
+	if err := c.CompleteTask("1"); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("%d requests, want 2", requests.Load())
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 2*time.Second {
+		t.Errorf("sleeps = %v, want the 2s Retry-After", *sleeps)
+	}
+}
+
+func TestDo_GivesUpOnLongRetryAfter(t *testing.T) {
+	c, requests, sleeps := newRetryClient(t, "60", http.StatusTooManyRequests)
+
+	if _, err := c.SyncAll(); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if requests.Load() != 1 || len(*sleeps) != 0 {
+		t.Errorf("%d requests, sleeps %v; want no waiting past maxRetryAfter", requests.Load(), *sleeps)
+	}
+}
+
+func TestDo_RetriesServerErrorsWithBackoff(t *testing.T) {
+	c, requests, sleeps := newRetryClient(t, "", http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	if _, err := c.SyncAll(); err != nil {
+		t.Fatalf("SyncAll: %v", err)
+	}
+	if requests.Load() != 3 || len(*sleeps) != 2 {
+		t.Fatalf("%d requests, sleeps %v; want 2 retries", requests.Load(), *sleeps)
+	}
+	// Doubling per attempt, plus up to 50% jitter
+	for i, d := range *sleeps {
+		base := 10 * time.Millisecond << i
+		if d < base || d > base+base/2 {
+			t.Errorf("sleep %d = %v, want between %v and %v", i, d, base, base+base/2)
+		}
+	}
+}
+
+func TestDo_DoesNotRetryNonIdempotent(t *testing.T) {
+	c, requests, _ := newRetryClient(t, "", http.StatusInternalServerError)
+
+	if err := c.CompleteTask("1"); !errors.Is(err, ErrServer) {
+		t.Fatalf("err = %v, want ErrServer", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("%d requests, want a single attempt", requests.Load())
+	}
+}
@@ -0,0 +1,86 @@
+package todoisttest
+
+import (
+	"alfredo-go/pkg/todoist"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Fixture is a generated Todoist account, for benchmarks that need more data
+// than a test would seed by hand
+type Fixture struct {
+	Tasks    []todoist.Task
+	Projects []todoist.Project
+	Sections []todoist.Section
+	Labels   []todoist.Label
+	Notes    []todoist.Note
+}
+
+var fixtureWords = strings.Fields(`
+	write read review send call email plan prepare update fix check book buy pay
+	clean organize draft schedule cancel renew order return finish start report
+	invoice meeting budget slides proposal contract dentist groceries laundry
+	car insurance taxes passport flight hotel birthday gift presentation demo
+	release deploy backup server database design roadmap newsletter blog video
+	podcast interview résumé café naïve façade garden kitchen garage bike team
+	client partner supplier quarterly weekly monthly annual notes agenda minutes`)
+
+// NewFixture generates n tasks spread over projects, sections and labels
+// (one for every 200, 50 and 300 tasks, and at least one of each), with
+// contents of two to six words, due dates around now and some subtasks and
+// comments. The same n always gives the same data.
+func NewFixture(n int, now time.Time) Fixture {
+	rng := rand.New(rand.NewSource(int64(n)))
+	var f Fixture
+
+	for i := range max(1, n/200) {
+		f.Projects = append(f.Projects, todoist.Project{
+			ID: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("Project %d", i)})
+	}
+	f.Projects[0].Name = "Inbox"
+	for i := range max(1, n/50) {
+		f.Sections = append(f.Sections, todoist.Section{
+			ID: fmt.Sprintf("s%d", i), Name: fmt.Sprintf("Section %d", i),
+			ProjectID: f.Projects[rng.Intn(len(f.Projects))].ID})
+	}
+	for i := range max(1, n/300) {
+		f.Labels = append(f.Labels, todoist.Label{ID: fmt.Sprintf("l%d", i), Name: fmt.Sprintf("label%d", i)})
+	}
+
+	for i := range n {
+		words := make([]string, 2+rng.Intn(5))
+		for j := range words {
+			words[j] = fixtureWords[rng.Intn(len(fixtureWords))]
+		}
+		t := todoist.Task{
+			ID:       fmt.Sprintf("t%d", i),
+			Content:  strings.Join(words, " "),
+			Priority: 1 + rng.Intn(4),
+		}
+		if sect := f.Sections[rng.Intn(len(f.Sections))]; rng.Intn(2) == 0 {
+			t.ProjectID, t.SectionID = sect.ProjectID, sect.ID
+		} else {
+			t.ProjectID = f.Projects[rng.Intn(len(f.Projects))].ID
+		}
+		for range rng.Intn(3) {
+			t.Labels = append(t.Labels, f.Labels[rng.Intn(len(f.Labels))].Name)
+		}
+		if rng.Intn(4) > 0 {
+			t.Due = &todoist.Due{Date: now.AddDate(0, 0, rng.Intn(30)-10).Format("2006-01-02")}
+		}
+		if rng.Intn(5) == 0 {
+			t.Description = fixtureWords[rng.Intn(len(fixtureWords))] + " details"
+		}
+		if i > 0 && rng.Intn(10) == 0 {
+			parent := f.Tasks[rng.Intn(i)]
+			t.ParentID, t.ProjectID, t.SectionID = parent.ID, parent.ProjectID, parent.SectionID
+		}
+		if rng.Intn(10) == 0 {
+			f.Notes = append(f.Notes, todoist.Note{ID: fmt.Sprintf("n%d", i), ItemID: t.ID, Content: "noted"})
+		}
+		f.Tasks = append(f.Tasks, t)
+	}
+	return f
+}
@@ -0,0 +1,522 @@
+// Package todoisttest provides an in-memory fake of the Todoist API endpoints
+// used by AlfreDo, for tests that exercise the client, cache and service
+// layers without network access.
+package todoisttest
+
+import (
+	"alfredo-go/pkg/todoist"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake Todoist API. It implements /api/v1/sync (reads, with
+// incremental sync tokens, and commands), task create/close/delete and
+// label creation. All methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	token   string
+	version int
+	nextID  int
+
+	tasks    []*entry[todoist.Task]
+	projects []*entry[todoist.Project]
+	sections []*entry[todoist.Section]
+	labels   []*entry[todoist.Label]
+	notes    []*entry[todoist.Note]
+	filters  []*entry[todoist.Filter]
+	stats    *todoist.StatsResponse
+	user     *todoist.UserInfo
+
+	failures   []int    // status codes to answer the next requests with
+	syncTokens []string // sync_token of every sync read, in order
+}
+
+// entry tracks the version at which an object last changed
+type entry[T any] struct {
+	obj     T
+	version int
+}
+
+// NewServer starts a fake server accepting the given API token
+func NewServer(token string) *Server {
+	s := &Server{token: token, nextID: 1000}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sync", s.handleSync)
+	mux.HandleFunc("POST /api/v1/tasks", s.handleCreateTask)
+	mux.HandleFunc("POST /api/v1/tasks/{id}/close", s.handleCloseTask)
+	mux.HandleFunc("DELETE /api/v1/tasks/{id}", s.handleDeleteTask)
+	mux.HandleFunc("POST /api/v1/labels", s.handleCreateLabel)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// AddTask seeds a task; an empty ID is assigned automatically
+func (s *Server) AddTask(t todoist.Task) todoist.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.ID == "" {
+		t.ID = s.newID()
+	}
+	s.tasks = append(s.tasks, &entry[todoist.Task]{t, s.bump()})
+	return t
+}
+
+// AddProject seeds a project
+func (s *Server) AddProject(p todoist.Project) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects = append(s.projects, &entry[todoist.Project]{p, s.bump()})
+}
+
+// AddSection seeds a section
+func (s *Server) AddSection(sect todoist.Section) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sections = append(s.sections, &entry[todoist.Section]{sect, s.bump()})
+}
+
+// AddLabel seeds a label
+func (s *Server) AddLabel(l todoist.Label) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.labels = append(s.labels, &entry[todoist.Label]{l, s.bump()})
+}
+
+// AddNote seeds a comment; an empty ID is assigned automatically
+func (s *Server) AddNote(n todoist.Note) todoist.Note {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n.ID == "" {
+		n.ID = s.newID()
+	}
+	s.notes = append(s.notes, &entry[todoist.Note]{n, s.bump()})
+	return n
+}
+
+// AddFilter seeds a saved filter; an empty ID is assigned automatically
+func (s *Server) AddFilter(f todoist.Filter) todoist.Filter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f.ID == "" {
+		f.ID = s.newID()
+	}
+	s.filters = append(s.filters, &entry[todoist.Filter]{f, s.bump()})
+	return f
+}
+
+// Notes returns the comments on a task
+func (s *Server) Notes(taskID string) []todoist.Note {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var notes []todoist.Note
+	for _, e := range s.notes {
+		if e.obj.ItemID == taskID && !e.obj.IsDeleted {
+			notes = append(notes, e.obj)
+		}
+	}
+	return notes
+}
+
+// SetUser sets the user object returned by full syncs
+func (s *Server) SetUser(u todoist.UserInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = &u
+}
+
+// Task returns the current server-side state of a task, including completed
+// and deleted ones
+func (s *Server) Task(id string) (todoist.Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.findTask(id); e != nil {
+		return e.obj, true
+	}
+	return todoist.Task{}, false
+}
+
+// ActiveTasks returns the tasks that are neither completed nor deleted
+func (s *Server) ActiveTasks() []todoist.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tasks []todoist.Task
+	for _, e := range s.tasks {
+		if !e.obj.Checked && !e.obj.IsDeleted {
+			tasks = append(tasks, e.obj)
+		}
+	}
+	return tasks
+}
+
+// Labels returns the names of all active labels
+func (s *Server) Labels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, e := range s.labels {
+		if !e.obj.IsDeleted {
+			names = append(names, e.obj.Name)
+		}
+	}
+	return names
+}
+
+// FailNext makes the next requests fail with the given status codes, in order
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// SyncTokens returns the sync_token sent with every sync read so far
+func (s *Server) SyncTokens() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.syncTokens...)
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.token {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		s.mu.Lock()
+		var status int
+		if len(s.failures) > 0 {
+			status, s.failures = s.failures[0], s.failures[1:]
+		}
+		s.mu.Unlock()
+		if status != 0 {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cmdJSON := r.PostForm.Get("commands"); cmdJSON != "" {
+		var cmds []todoist.Command
+		if err := json.Unmarshal([]byte(cmdJSON), &cmds); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, s.runCommands(cmds))
+		return
+	}
+
+	token := r.PostForm.Get("sync_token")
+	s.syncTokens = append(s.syncTokens, token)
+	since, err := strconv.Atoi(strings.TrimPrefix(token, "v"))
+	full := token == "*" || err != nil || since > s.version
+
+	resp := todoist.SyncAllResponse{
+		SyncToken: fmt.Sprintf("v%d", s.version),
+		FullSync:  full,
+		Stats:     s.stats,
+		User:      s.user,
+	}
+	resp.Items = changed(s.tasks, since, full, func(t todoist.Task) bool { return t.Checked || t.IsDeleted })
+	resp.Projects = changed(s.projects, since, full, func(p todoist.Project) bool { return p.IsDeleted })
+	resp.Sections = changed(s.sections, since, full, func(sect todoist.Section) bool { return sect.IsDeleted })
+	resp.Labels = changed(s.labels, since, full, func(l todoist.Label) bool { return l.IsDeleted })
+	resp.Notes = changed(s.notes, since, full, func(n todoist.Note) bool { return n.IsDeleted })
+	resp.Filters = changed(s.filters, since, full, func(f todoist.Filter) bool { return f.IsDeleted })
+	writeJSON(w, resp)
+}
+
+// changed returns every live object for a full sync, or every object changed
+// after version since (including removed ones) for an incremental sync
+func changed[T any](entries []*entry[T], since int, full bool, removed func(T) bool) []T {
+	result := []T{}
+	for _, e := range entries {
+		if full && !removed(e.obj) || !full && e.version > since {
+			result = append(result, e.obj)
+		}
+	}
+	return result
+}
+
+func (s *Server) runCommands(cmds []todoist.Command) todoist.CommandsResponse {
+	resp := todoist.CommandsResponse{
+		SyncStatus:    map[string]json.RawMessage{},
+		TempIDMapping: map[string]string{},
+	}
+	resolve := func(id string) string {
+		if real, ok := resp.TempIDMapping[id]; ok {
+			return real
+		}
+		return id
+	}
+
+	for _, cmd := range cmds {
+		var args struct {
+			ID        string `json:"id"`
+			Name      string `json:"name"`
+			ProjectID string `json:"project_id"`
+			SectionID string `json:"section_id"`
+			ParentID  string `json:"parent_id"`
+			ItemID    string `json:"item_id"`
+			Content   string `json:"content"`
+		}
+		raw, _ := json.Marshal(cmd.Args)
+		json.Unmarshal(raw, &args)
+		args.ID = resolve(args.ID)
+
+		var err error
+		switch cmd.Type {
+		case "item_add":
+			var t todoist.Task
+			json.Unmarshal(raw, &t)
+			t.ID = s.newID()
+			t.ParentID = resolve(t.ParentID)
+			if t.Priority == 0 {
+				t.Priority = 1
+			}
+			s.inheritParent(&t)
+			s.tasks = append(s.tasks, &entry[todoist.Task]{t, s.bump()})
+			resp.TempIDMapping[cmd.TempID] = t.ID
+		case "item_update":
+			err = s.updateTask(args.ID, func(t *todoist.Task) {
+				// Only the keys present in args change; dates are replaced
+				// as a whole
+				if _, ok := cmd.Args["due"]; ok {
+					t.Due = nil
+				}
+				if _, ok := cmd.Args["deadline"]; ok {
+					t.Deadline = nil
+				}
+				json.Unmarshal(raw, t)
+				t.ID = args.ID
+			})
+		case "item_move":
+			err = s.updateTask(args.ID, func(t *todoist.Task) {
+				switch {
+				case args.ParentID != "":
+					t.ParentID = resolve(args.ParentID)
+					s.inheritParent(t)
+				case args.SectionID != "":
+					t.SectionID = args.SectionID
+					t.ProjectID = s.sectionProject(args.SectionID)
+					t.ParentID = ""
+				default:
+					t.ProjectID = args.ProjectID
+					t.SectionID = ""
+					t.ParentID = ""
+				}
+			})
+		case "item_close":
+			err = s.updateTask(args.ID, closeTask)
+		case "item_uncomplete":
+			err = s.updateTask(args.ID, func(t *todoist.Task) { t.Checked = false })
+		case "item_delete":
+			err = s.updateTask(args.ID, func(t *todoist.Task) { t.IsDeleted = true })
+		case "note_add":
+			itemID := resolve(args.ItemID)
+			if s.findTask(itemID) == nil {
+				err = fmt.Errorf("task %s not found", itemID)
+				break
+			}
+			id := s.newID()
+			s.notes = append(s.notes, &entry[todoist.Note]{todoist.Note{
+				ID: id, ItemID: itemID, Content: args.Content, PostedAt: time.Now().UTC().Format(time.RFC3339),
+			}, s.bump()})
+			resp.TempIDMapping[cmd.TempID] = id
+		case "label_add":
+			id := s.newID()
+			s.labels = append(s.labels, &entry[todoist.Label]{todoist.Label{ID: id, Name: args.Name}, s.bump()})
+			resp.TempIDMapping[cmd.TempID] = id
+		default:
+			err = fmt.Errorf("unknown command type %s", cmd.Type)
+		}
+
+		if err != nil {
+			resp.SyncStatus[cmd.UUID], _ = json.Marshal(map[string]any{
+				"error_code": 20, "error": err.Error(), "http_code": 400,
+			})
+		} else {
+			resp.SyncStatus[cmd.UUID] = json.RawMessage(`"ok"`)
+		}
+	}
+	return resp
+}
+
+func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Content     string   `json:"content"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+		ProjectID   string   `json:"project_id"`
+		SectionID   string   `json:"section_id"`
+		ParentID    string   `json:"parent_id"`
+		Priority    int      `json:"priority"`
+		DueString   string   `json:"due_string"`
+		DueDate     string   `json:"due_date"`
+		DueDatetime string   `json:"due_datetime"`
+		Deadline    string   `json:"deadline_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := todoist.Task{
+		ID:          s.newID(),
+		Content:     payload.Content,
+		Description: payload.Description,
+		Labels:      payload.Labels,
+		ProjectID:   payload.ProjectID,
+		SectionID:   payload.SectionID,
+		ParentID:    payload.ParentID,
+		Priority:    max(payload.Priority, 1),
+	}
+	// No natural language parsing here: a due string is stored as its date
+	switch {
+	case payload.DueDatetime != "":
+		t.Due = &todoist.Due{Date: payload.DueDatetime}
+	case payload.DueDate != "":
+		t.Due = &todoist.Due{Date: payload.DueDate}
+	case payload.DueString != "":
+		t.Due = &todoist.Due{Date: payload.DueString}
+	}
+	if payload.Deadline != "" {
+		t.Deadline = &todoist.Deadline{Date: payload.Deadline}
+	}
+	s.inheritParent(&t)
+	s.tasks = append(s.tasks, &entry[todoist.Task]{t, s.bump()})
+	writeJSON(w, t)
+}
+
+func (s *Server) handleCloseTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.updateTask(r.PathValue("id"), closeTask); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.updateTask(r.PathValue("id"), func(t *todoist.Task) { t.IsDeleted = true }); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCreateLabel(w http.ResponseWriter, r *http.Request) {
+	var l todoist.Label
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.ID = s.newID()
+	s.labels = append(s.labels, &entry[todoist.Label]{l, s.bump()})
+	writeJSON(w, l)
+}
+
+// updateTask applies fn to a task and bumps its version. The caller holds mu.
+func (s *Server) updateTask(id string, fn func(*todoist.Task)) error {
+	e := s.findTask(id)
+	if e == nil {
+		return fmt.Errorf("task %s not found", id)
+	}
+	fn(&e.obj)
+	e.version = s.bump()
+	return nil
+}
+
+func (s *Server) findTask(id string) *entry[todoist.Task] {
+	for _, e := range s.tasks {
+		if e.obj.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// closeTask completes a task. Recurring tasks stay open and move to their
+// next occurrence: a week later if the rule mentions "week", else a day later.
+func closeTask(t *todoist.Task) {
+	if t.Due == nil || !t.Due.IsRecurring {
+		t.Checked = true
+		return
+	}
+	date, err := time.Parse("2006-01-02", t.Due.Date)
+	if err != nil {
+		return
+	}
+	days := 1
+	if strings.Contains(t.Due.String, "week") {
+		days = 7
+	}
+	t.Due.Date = date.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+// inheritParent puts a subtask in its parent's project and section
+func (s *Server) inheritParent(t *todoist.Task) {
+	if t.ParentID == "" {
+		return
+	}
+	if parent := s.findTask(t.ParentID); parent != nil {
+		t.ProjectID = parent.obj.ProjectID
+		t.SectionID = parent.obj.SectionID
+	}
+}
+
+func (s *Server) sectionProject(sectionID string) string {
+	for _, e := range s.sections {
+		if e.obj.ID == sectionID {
+			return e.obj.ProjectID
+		}
+	}
+	return ""
+}
+
+func (s *Server) bump() int {
+	s.version++
+	return s.version
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
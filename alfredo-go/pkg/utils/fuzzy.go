@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fold lowercases text and strips accents, so "Café" and "cafe" compare equal
+func Fold(text string) string {
+	if isASCII(text) {
+		// Nothing to normalize or strip
+		return strings.ToLower(strings.TrimSpace(text))
+	}
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, NormalizeUnicode(text))
+	if err != nil {
+		folded = NormalizeUnicode(text)
+	}
+	return strings.ToLower(folded)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// FuzzyScore scores how well query matches text, from 0 (no match) to 1.
+// Every word of query has to match, ignoring case and accents: as a
+// substring (best), as an abbreviation of a word ("mtg" for "meeting"), or as
+// a word with a typo or two ("reprot" for "report"). The score is the
+// average over the query words.
+func FuzzyScore(query, text string) float64 {
+	qWords := strings.Fields(Fold(query))
+	if len(qWords) == 0 {
+		return 1
+	}
+	text = Fold(text)
+	words := Words(text)
+
+	total := 0.0
+	for _, q := range qWords {
+		score := wordScore(q, text, words)
+		if score == 0 {
+			return 0
+		}
+		total += score
+	}
+	return total / float64(len(qWords))
+}
+
+// Words splits text into the runs of letters and digits FuzzyScore matches
+// query words against
+func Words(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) })
+}
+
+// FuzzyWordScore scores a folded query word against a single folded word,
+// as FuzzyScore does for each word of the text. A query word made of letters
+// and digits only scores above 0 against a text exactly when it does so
+// against one of the text's words.
+func FuzzyWordScore(q, word string) float64 {
+	return wordScore(q, word, []string{word})
+}
+
+// wordScore scores a single query word against the folded text and its words
+func wordScore(q, text string, words []string) float64 {
+	if i := strings.Index(text, q); i >= 0 {
+		// Matches at the start of a word beat ones in the middle
+		if r, _ := utf8.DecodeLastRuneInString(text[:i]); i == 0 || !isWordRune(r) {
+			return 1
+		}
+		return 0.9
+	}
+
+	best := 0.0
+	qr := []rune(q)
+	for _, w := range words {
+		wr := []rune(w)
+		if isAbbreviation(qr, wr) {
+			best = max(best, 0.5+0.3*float64(len(qr))/float64(len(wr)))
+		}
+		if typos := maxTypos(len(qr)); typos > 0 {
+			// Compare with the whole word, and with its start for words still being typed
+			d := editDistance(qr, wr)
+			if len(wr) > len(qr) {
+				d = min(d, editDistance(qr, wr[:len(qr)]))
+			}
+			if d <= typos {
+				best = max(best, 0.6-0.1*float64(d))
+			}
+		}
+	}
+	return best
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isAbbreviation reports whether q is a subsequence of w starting with the
+// same letter, like "mtg" in "meeting"
+func isAbbreviation(q, w []rune) bool {
+	if len(q) < 2 || len(q) >= len(w) || q[0] != w[0] {
+		return false
+	}
+	i := 0
+	for _, r := range w {
+		if r == q[i] {
+			i++
+			if i == len(q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxTypos is how many typos a query word of n letters may contain
+func maxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and swaps of adjacent letters
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,80 @@
+package utils
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"Café":           "cafe",
+		"  Über Straße ": "uber straße",
+		"naïve RÉSUMÉ":   "naive resume",
+		"plain":          "plain",
+	}
+	for in, want := range tests {
+		if got := Fold(in); got != want {
+			t.Errorf("Fold(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, text string
+		match       bool
+	}{
+		{"report", "Write report", true},
+		{"REP", "Write report", true},
+		{"cafe", "Meet at the Café", true},
+		{"mtg prep", "Meeting preparation", true},
+		{"reprot", "Write report", true},
+		{"prepration", "Meeting preparation", true},
+		{"budgte", "Budget review", true},
+		{"meet", "Meeting", true},
+		{"mtg", "Mow the grass", false},
+		{"xyz", "Write report", false},
+		{"report milk", "Write report", false},
+		{"cat", "Call Acme", false},
+		{"", "anything", true},
+	}
+	for _, tt := range tests {
+		if got := FuzzyScore(tt.query, tt.text); (got > 0) != tt.match {
+			t.Errorf("FuzzyScore(%q, %q) = %v, want match %v", tt.query, tt.text, got, tt.match)
+		}
+	}
+}
+
+func TestFuzzyScoreOrdering(t *testing.T) {
+	// Better kinds of match score higher
+	ordered := []struct{ query, text string }{
+		{"report", "report due"},
+		{"port", "report due"},
+		{"rpt", "report due"},
+		{"reprot", "report due"},
+	}
+	prev := 2.0
+	for _, tt := range ordered {
+		score := FuzzyScore(tt.query, tt.text)
+		if score <= 0 || score >= prev {
+			t.Errorf("FuzzyScore(%q, %q) = %v, want in (0, %v)", tt.query, tt.text, score, prev)
+		}
+		prev = score
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"report", "report", 0},
+		{"reprot", "report", 1},
+		{"repot", "report", 1},
+		{"reeport", "report", 1},
+		{"rapport", "report", 2},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}